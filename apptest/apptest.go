@@ -0,0 +1,264 @@
+// Package apptest boots an Application in-process for tests. Fake servers and
+// workers record their Serve, Run, Stop and GracefulStop calls next to the
+// lifecycle hooks, signals are injected instead of sent to the process, and the
+// shutdown is checked to finish within a deadline without leaking goroutines.
+//
+//	app := apptest.New(t)
+//	http := apptest.NewServer(app.Recorder, "http")
+//	app.Start(http)
+//	app.Recorder.Wait("serve http", time.Second)
+//	app.Shutdown(syscall.SIGINT, 5*time.Second)
+//	app.AssertOrder("hook beforeServe", "serve http", "hook beforeStop", "graceful-stop http", "hook afterStop")
+package apptest
+
+import (
+	"copy"
+	"copy/pkg/flag"
+	"copy/pkg/server"
+	"copy/pkg/util/xhook"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+var stageNames = map[uint32]string{
+	copy.StageBeforeStart: "beforeStart",
+	copy.StageAfterStart:  "afterStart",
+	copy.StageBeforeServe: "beforeServe",
+	copy.StageAfterServe:  "afterServe",
+	copy.StageBeforeStop:  "beforeStop",
+	copy.StageAfterStop:   "afterStop",
+}
+
+// App is an Application under test.
+type App struct {
+	*copy.Application
+	Recorder *Recorder
+	Signals  *Signals
+
+	t          testing.TB
+	goroutines int
+	startedUp  bool
+	done       chan struct{}
+	err        error
+}
+
+// New creates an Application with fake signals, an empty flagset and no governor,
+// opts are applied after them. Every lifecycle hook stage is recorded as "hook <stage>".
+func New(t testing.TB, opts ...copy.Option) *App {
+	t.Helper()
+	a := &App{
+		Recorder:   &Recorder{},
+		Signals:    NewSignals(),
+		t:          t,
+		goroutines: runtime.NumGoroutine(),
+	}
+	options := append([]copy.Option{
+		copy.WithSignalSource(a.Signals),
+		copy.WithFlagSet(flag.NewFlagSet(t.Name(), nil)),
+		copy.WithDisable(copy.DisableDefaultGovernor),
+	}, opts...)
+	app, err := copy.New(options...)
+	if err != nil {
+		t.Fatalf("new application: %v", err)
+	}
+	a.Application = app
+	for stage, name := range stageNames {
+		event := "hook " + name
+		hook := xhook.Func(func() error {
+			a.Recorder.Record(event)
+			return nil
+		})
+		hook.Name = event
+		if err := app.RegisterHook(stage, hook); err != nil {
+			t.Fatalf("register hook %s: %v", name, err)
+		}
+	}
+	return a
+}
+
+// Startup runs the startup stages of the Application, see Application.Startup.
+func (a *App) Startup(fns ...func() error) error {
+	a.startedUp = true
+	return a.Application.Startup(fns...)
+}
+
+// Start starts up the Application unless Startup was called, and runs it with servers
+// in a new goroutine.
+func (a *App) Start(servers ...server.Server) {
+	a.t.Helper()
+	if !a.startedUp {
+		if err := a.Startup(); err != nil {
+			a.t.Fatalf("startup: %v", err)
+		}
+	}
+	a.done = make(chan struct{})
+	go func() {
+		defer close(a.done)
+		a.err = a.Run(servers...)
+	}()
+}
+
+// Signal injects sig once the Application listens to it.
+func (a *App) Signal(sig os.Signal) {
+	a.t.Helper()
+	if err := a.Signals.Send(sig, 5*time.Second); err != nil {
+		a.t.Fatal(err)
+	}
+}
+
+// Shutdown injects sig and waits for the Application to stop within deadline, see WaitStopped.
+func (a *App) Shutdown(sig os.Signal, deadline time.Duration) error {
+	a.t.Helper()
+	a.Signal(sig)
+	return a.WaitStopped(deadline)
+}
+
+// WaitStopped waits for Run to return within deadline, and for the goroutines started
+// since New to exit within the rest of deadline. The error of Run is returned.
+func (a *App) WaitStopped(deadline time.Duration) error {
+	a.t.Helper()
+	if a.done == nil {
+		a.t.Fatal("application not started")
+	}
+	expire := time.Now().Add(deadline)
+	select {
+	case <-a.done:
+	case <-time.After(deadline):
+		a.t.Fatalf("application not stopped within %v, events: %v", deadline, a.Recorder.Events())
+	}
+	for runtime.NumGoroutine() > a.goroutines {
+		if time.Now().After(expire) {
+			buf := make([]byte, 1<<20)
+			buf = buf[:runtime.Stack(buf, true)]
+			a.t.Errorf("goroutines leaked, %d running, %d before:\n%s", runtime.NumGoroutine(), a.goroutines, buf)
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return a.err
+}
+
+// AssertOrder fails the test unless events were recorded in the given order,
+// other events may be recorded between them.
+func (a *App) AssertOrder(events ...string) bool {
+	a.t.Helper()
+	recorded := a.Recorder.Events()
+	i := 0
+	for _, event := range recorded {
+		if i < len(events) && event == events[i] {
+			i++
+		}
+	}
+	if i < len(events) {
+		a.t.Errorf("event %q not recorded in order %v, recorded: %v", events[i], events, recorded)
+		return false
+	}
+	return true
+}
+
+// Recorder records lifecycle events in the order they happen.
+type Recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+// Record appends event.
+func (r *Recorder) Record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Events returns a copy of the recorded events.
+func (r *Recorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+// Wait waits for event to be recorded, false if it is not within timeout.
+func (r *Recorder) Wait(event string, timeout time.Duration) bool {
+	expire := time.Now().Add(timeout)
+	for r.Count(event) == 0 {
+		if time.Now().After(expire) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+// Count returns how many times event was recorded.
+func (r *Recorder) Count(event string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int
+	for _, e := range r.events {
+		if e == event {
+			n++
+		}
+	}
+	return n
+}
+
+// Signals is a signals.Source fed by Send instead of the process.
+type Signals struct {
+	mu       sync.Mutex
+	c        chan<- os.Signal
+	notified map[os.Signal]bool
+	changed  chan struct{}
+}
+
+// NewSignals creates a fake signal source.
+func NewSignals() *Signals {
+	return &Signals{notified: make(map[os.Signal]bool), changed: make(chan struct{})}
+}
+
+// Notify implements signals.Source.
+func (s *Signals) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c = c
+	for _, sig := range sig {
+		s.notified[sig] = true
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Stop implements signals.Source.
+func (s *Signals) Stop(c chan<- os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.c == c {
+		s.c = nil
+		s.notified = make(map[os.Signal]bool)
+	}
+}
+
+// Send delivers sig once it is listened, or fails after timeout.
+func (s *Signals) Send(sig os.Signal, timeout time.Duration) error {
+	expire := time.After(timeout)
+	for {
+		s.mu.Lock()
+		c, ok, changed := s.c, s.notified[sig], s.changed
+		s.mu.Unlock()
+		if c != nil && ok {
+			select {
+			case c <- sig:
+				return nil
+			case <-expire:
+				return fmt.Errorf("signal %v not received within %v", sig, timeout)
+			}
+		}
+		select {
+		case <-changed:
+		case <-expire:
+			return fmt.Errorf("signal %v not listened within %v", sig, timeout)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package apptest
+
+import (
+	"context"
+	"copy"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApp_GracefulShutdown(t *testing.T) {
+	app := New(t)
+	http := NewServer(app.Recorder, "http")
+	grpc := NewServer(app.Recorder, "grpc")
+	assert.Nil(t, app.Schedule(NewWorker(app.Recorder, "consumer")))
+
+	app.Start(http, grpc)
+	for _, event := range []string{"serve http", "serve grpc", "run consumer"} {
+		assert.True(t, app.Recorder.Wait(event, 5*time.Second), event)
+	}
+	app.Signal(syscall.SIGINT)
+	assert.Nil(t, app.WaitStopped(5*time.Second))
+
+	app.AssertOrder("hook beforeStart", "hook afterStart", "hook beforeServe", "hook afterServe", "hook beforeStop", "hook afterStop")
+	app.AssertOrder("serve http", "hook beforeStop", "graceful-stop http", "hook afterStop")
+	app.AssertOrder("serve grpc", "graceful-stop grpc")
+	app.AssertOrder("run consumer", "hook beforeStop", "stop consumer", "hook afterStop")
+	assert.Equal(t, 0, app.Recorder.Count("stop http"))
+}
+
+func TestApp_ForcedStop(t *testing.T) {
+	app := New(t)
+	app.SetShutdownTimeout(50 * time.Millisecond)
+	slow := NewServer(app.Recorder, "slow")
+	slow.StopDelay = time.Minute
+
+	app.Start(slow)
+	assert.True(t, app.Recorder.Wait("serve slow", 5*time.Second))
+	assert.Nil(t, app.Shutdown(syscall.SIGINT, 5*time.Second))
+	report, err := app.GracefulShutdown(context.Background())
+	assert.Error(t, err)
+	assert.True(t, report.Servers[0].Forced)
+	app.AssertOrder("graceful-stop slow", "stop slow", "hook afterStop")
+}
+
+func TestApp_Quit(t *testing.T) {
+	app := New(t)
+	app.Start(NewServer(app.Recorder, "http"))
+	assert.True(t, app.Recorder.Wait("serve http", 5*time.Second))
+	assert.Nil(t, app.Shutdown(syscall.SIGQUIT, 5*time.Second))
+	app.AssertOrder("serve http", "hook beforeStop", "stop http", "hook afterStop")
+	assert.Equal(t, 0, app.Recorder.Count("graceful-stop http"))
+}
+
+func TestApp_Registry(t *testing.T) {
+	app := New(t)
+	app.SetRegistry(NewRegistry(app.Recorder), NewRegistry(app.Recorder))
+	app.Start(NewServer(app.Recorder, "http"))
+	assert.True(t, app.Recorder.Wait("register apptest://http", 5*time.Second))
+	assert.Nil(t, app.Shutdown(syscall.SIGINT, 5*time.Second))
+
+	app.AssertOrder("serve http", "register apptest://http", "hook beforeStop", "unregister apptest://http", "graceful-stop http")
+	assert.Equal(t, 2, app.Recorder.Count("register apptest://http"))
+	assert.Equal(t, 2, app.Recorder.Count("unregister apptest://http"))
+}
+
+func TestApp_UpgradeKeepsRegistrations(t *testing.T) {
+	upgraded := make(chan struct{})
+	app := New(t, copy.WithUpgrader(func(context.Context, ...net.Listener) (*os.Process, error) {
+		close(upgraded)
+		return &os.Process{Pid: os.Getpid() + 1}, nil
+	}))
+	app.SetUpgradeSignal(syscall.SIGUSR2)
+	app.SetRegistry(NewRegistry(app.Recorder))
+	app.Start(NewServer(app.Recorder, "http"))
+	assert.True(t, app.Recorder.Wait("register apptest://http", 5*time.Second))
+
+	// the new process serves the same label, it stays registered
+	app.Signal(syscall.SIGUSR2)
+	<-upgraded
+	assert.Nil(t, app.WaitStopped(5*time.Second))
+	app.AssertOrder("register apptest://http", "hook beforeStop", "graceful-stop http", "hook afterStop")
+	assert.Equal(t, 0, app.Recorder.Count("unregister apptest://http"))
+}
@@ -0,0 +1,132 @@
+package apptest
+
+import (
+	"context"
+	"copy/pkg/registry"
+	"copy/pkg/server"
+	"sync"
+	"time"
+)
+
+// Server is a fake server.Server recording "serve <name>", "stop <name>" and
+// "graceful-stop <name>". Serve blocks until the server is stopped, the server
+// is ready once Serve is called, see server.ReadyNotifier.
+type Server struct {
+	Name string
+	// ServeErr is returned by Serve at once if not nil
+	ServeErr error
+	// StopDelay holds GracefulStop until it passes or the ctx is done
+	StopDelay time.Duration
+
+	recorder  *Recorder
+	once      sync.Once
+	stopped   chan struct{}
+	readyOnce sync.Once
+	ready     chan struct{}
+}
+
+// NewServer creates a fake server recording to recorder.
+func NewServer(recorder *Recorder, name string) *Server {
+	return &Server{Name: name, recorder: recorder, stopped: make(chan struct{}), ready: make(chan struct{})}
+}
+
+// Serve ...
+func (s *Server) Serve() error {
+	s.recorder.Record("serve " + s.Name)
+	if s.ServeErr != nil {
+		return s.ServeErr
+	}
+	s.readyOnce.Do(func() { close(s.ready) })
+	<-s.stopped
+	return nil
+}
+
+// Ready ...
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Stop ...
+func (s *Server) Stop() error {
+	s.recorder.Record("stop " + s.Name)
+	s.once.Do(func() { close(s.stopped) })
+	return nil
+}
+
+// GracefulStop ...
+func (s *Server) GracefulStop(ctx context.Context) error {
+	s.recorder.Record("graceful-stop " + s.Name)
+	if s.StopDelay > 0 {
+		timer := time.NewTimer(s.StopDelay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	s.once.Do(func() { close(s.stopped) })
+	return nil
+}
+
+// Info ...
+func (s *Server) Info() *server.ServiceInfo {
+	return &server.ServiceInfo{Name: s.Name, Scheme: "apptest", Address: s.Name}
+}
+
+// Worker is a fake worker.Worker recording "run <name>" and "stop <name>".
+// Run blocks until the worker is stopped.
+type Worker struct {
+	Name string
+	// RunErr is returned by Run at once if not nil
+	RunErr error
+
+	recorder *Recorder
+	once     sync.Once
+	stopped  chan struct{}
+}
+
+// NewWorker creates a fake worker recording to recorder.
+func NewWorker(recorder *Recorder, name string) *Worker {
+	return &Worker{Name: name, recorder: recorder, stopped: make(chan struct{})}
+}
+
+// Run ...
+func (w *Worker) Run() error {
+	w.recorder.Record("run " + w.Name)
+	if w.RunErr != nil {
+		return w.RunErr
+	}
+	<-w.stopped
+	return nil
+}
+
+// Stop ...
+func (w *Worker) Stop() error {
+	w.recorder.Record("stop " + w.Name)
+	w.once.Do(func() { close(w.stopped) })
+	return nil
+}
+
+// Registry is a fake registry.Registry recording "register <label>" and "unregister <label>".
+type Registry struct {
+	registry.Nop
+	recorder *Recorder
+}
+
+// NewRegistry creates a fake registry recording to recorder.
+func NewRegistry(recorder *Recorder) *Registry {
+	return &Registry{recorder: recorder}
+}
+
+// RegisterService ...
+func (r *Registry) RegisterService(_ context.Context, info *server.ServiceInfo) error {
+	r.recorder.Record("register " + info.Label())
+	return nil
+}
+
+// UnregisterService ...
+func (r *Registry) UnregisterService(_ context.Context, info *server.ServiceInfo) error {
+	r.recorder.Record("unregister " + info.Label())
+	return nil
+}
@@ -2,4 +2,15 @@ module copy
 
 go 1.15
 
-require github.com/douyu/jupiter v0.2.5 // indirect
+require (
+	github.com/BurntSushi/toml v0.3.1
+	github.com/douyu/jupiter v0.2.5
+	github.com/fsnotify/fsnotify v1.4.9
+	github.com/mitchellh/mapstructure v1.3.2
+	github.com/prometheus/client_golang v1.6.0
+	github.com/stretchr/testify v1.6.1
+	go.uber.org/multierr v1.5.0
+	go.uber.org/zap v1.15.0
+	golang.org/x/sync v0.0.0-20200625203802-6e8e738ad208
+	gopkg.in/yaml.v2 v2.3.0
+)
@@ -0,0 +1,123 @@
+package copy
+
+import (
+	"copy/pkg/server/governor"
+	"copy/pkg/xlog"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/douyu/jupiter/pkg/ecode"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// initGovernor serves the governor server of jupiter.server.governor, which is served
+// and registered as the other servers. Its address is logged, as a warning if the port
+// is a free one, and told by Governor().Info(). Besides the routes of the governor package,
+// it serves the framework metrics and the servers, workers, jobs and cycle tasks of the app.
+func (app *Application) initGovernor() error {
+	if app.isDisable(DisableDefaultGovernor) {
+		app.logger.Info("defualt governor disable", xlog.FieldMod(ecode.ModApp))
+		return nil
+	}
+
+	config := governor.StdConfig("governor")
+	if !config.Enable {
+		return nil
+	}
+	s, err := config.WithLogger(app.logger).Build()
+	if err != nil {
+		return err
+	}
+	s.Handle("/metrics", promhttp.HandlerFor(prometheus.Gatherers{prometheus.DefaultGatherer, app.metrics}, promhttp.HandlerOpts{}))
+	s.HandleFunc("/servers", app.handleServers)
+	s.HandleFunc("/workers", func(w http.ResponseWriter, r *http.Request) {
+		governor.WriteJSON(w, r, app.WorkerStatus())
+	})
+	s.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		governor.WriteJSON(w, r, app.jobNames())
+	})
+	s.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
+		governor.WriteJSON(w, r, app.cycle.Tasks())
+	})
+	app.governor = s
+	if config.Port == 0 {
+		// a free port differs on every start, the warning tells where the governor is
+		app.logger.Warn("start governor on a free port, set jupiter.server.governor.port to fix it", xlog.FieldMod(ecode.ModApp), xlog.FieldAddr(s.Info().Label()))
+	} else {
+		app.logger.Info("start governor", xlog.FieldMod(ecode.ModApp), xlog.FieldAddr(s.Info().Label()))
+	}
+	return app.Serve(s)
+}
+
+// handleServers lists the infos of every server. POST label with any of enable,
+// healthy and weight updates the server of label, see UpdateServer.
+func (app *Application) handleServers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var update ServerUpdate
+		if v := r.FormValue("enable"); v != "" {
+			enable, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "invalid enable: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			update.Enable = &enable
+		}
+		if v := r.FormValue("healthy"); v != "" {
+			healthy, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "invalid healthy: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			update.Healthy = &healthy
+		}
+		if v := r.FormValue("weight"); v != "" {
+			weight, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				http.Error(w, "invalid weight: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			update.Weight = &weight
+		}
+		operator := r.Header.Get("X-Operator")
+		if operator == "" {
+			operator = r.RemoteAddr
+		}
+		label := r.FormValue("label")
+		app.logger.Info("admin update server", xlog.FieldMod(ecode.ModApp), xlog.FieldAddr(label), xlog.String("operator", operator), xlog.String("form", r.Form.Encode()))
+		if _, err := app.UpdateServer(label, update); err != nil {
+			code := http.StatusBadRequest
+			if errors.Is(err, ErrServerNotFound) {
+				code = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	governor.WriteJSON(w, r, app.serviceInfos())
+}
+
+// Governor returns the governor server, nil if it is disabled or before startup.
+// Routes can be added to it before it is served.
+func (app *Application) Governor() *governor.Server {
+	return app.governor
+}
+
+func (app *Application) jobNames() []string {
+	app.smu.RLock()
+	defer app.smu.RUnlock()
+	names := make([]string, 0, len(app.jobs))
+	for name := range app.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
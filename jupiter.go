@@ -2,26 +2,42 @@ package copy
 
 import (
 	"context"
+	"copy/pkg"
+	"copy/pkg/conf"
+	_ "copy/pkg/datasource/file"
+	_ "copy/pkg/datasource/http"
+	_ "copy/pkg/datasource/kv"
+	"copy/pkg/datasource/manager"
 	"copy/pkg/flag"
+	"copy/pkg/registry"
+	"copy/pkg/registry/compound"
 	"copy/pkg/server"
-	"copy/pkg/util/xdefer"
+	"copy/pkg/server/governor"
+	"copy/pkg/signals"
+	"copy/pkg/upgrade"
+	"copy/pkg/util/xhook"
+	"copy/pkg/util/xstage"
+	"copy/pkg/util/xtime"
 	"copy/pkg/worker"
 	"copy/pkg/worker/xjob"
+	"copy/pkg/worker/xsupervisor"
 	"copy/pkg/xlog"
+	"errors"
 	"fmt"
 	"github.com/BurntSushi/toml"
-	"github.com/douyu/jupiter"
-	"github.com/douyu/jupiter/pkg/conf"
 	"github.com/douyu/jupiter/pkg/ecode"
-	"github.com/douyu/jupiter/pkg/registry"
-	"github.com/douyu/jupiter/pkg/server/governor"
+	"github.com/douyu/jupiter/pkg/util/xcast"
 	"github.com/douyu/jupiter/pkg/util/xgo"
 	xlog2 "github.com/douyu/jupiter/pkg/xlog"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/multierr"
+	"io"
+	"net"
 	"os"
-	"os/signal"
+	"path/filepath"
+	"strings"
 	"sync"
-	"syscall"
+	"time"
 )
 
 const (
@@ -29,53 +45,135 @@ const (
 	StageAfterStop uint32 = iota + 1
 	//StageBeforeStop before app stop
 	StageBeforeStop
+	//StageBeforeStart before app startup stages
+	StageBeforeStart
+	//StageAfterStart after app startup stages
+	StageAfterStart
+	//StageBeforeServe before servers and workers start
+	StageBeforeServe
+	//StageAfterServe after servers and workers are started
+	StageAfterServe
 )
 
+var stageNames = map[uint32]string{
+	StageAfterStop:   "afterStop",
+	StageBeforeStop:  "beforeStop",
+	StageBeforeStart: "beforeStart",
+	StageAfterStart:  "afterStart",
+	StageBeforeServe: "beforeServe",
+	StageAfterServe:  "afterServe",
+}
+
 // Application is the framework's instance, it contains the servers, workers, client and configuration settings.
 // Create an instance of Application, by using &Application{}
 type Application struct {
-	cycle        *Cycle
-	smu          *sync.RWMutex
-	initOnce     sync.Once
-	startupOnce  sync.Once
-	stopOnce     sync.Once
-	servers      []server.Server
-	workers      []worker.Worker
-	jobs         map[string]xjob.Runner
-	logger       *xlog.Logger
-	registerer   prometheus.Registry
-	hooks        map[uint32]*xdefer.DeferStack
-	configParser conf.Unmarshaller
-	disableMap   map[jupiter.Disable]bool
-}
-
-func New(fns ...func() error) (*Application, error) {
+	cycle         *Cycle
+	cyclePolicy   CyclePolicy
+	smu           *sync.RWMutex
+	initOnce      sync.Once
+	startupOnce   sync.Once
+	stopOnce      sync.Once
+	servers       []server.Server
+	workers       []worker.Worker
+	jobs          map[string]xjob.RunnerE
+	logger        *xlog.Logger
+	registries    []registry.Registry
+	governor      *governor.Server
+	registerer    *compound.Registry
+	metrics       *prometheus.Registry
+	lifecycle     *appMetrics
+	clock         xtime.Clock
+	flags         *flag.FlagSet
+	signalSource  signals.Source
+	hooks         map[uint32]*xhook.Hooks
+	configParser  conf.Unmarshaller
+	disableMap    map[Disable]bool
+	signals       *signals.Router
+	upgradeSignal os.Signal
+	upgrader      func(ctx context.Context, listeners ...net.Listener) (*os.Process, error)
+	// exit ends the process in RunAndExit, os.Exit but in tests
+	exit          func(code int)
+	configSources map[string]conf.DataSource
+	// shutdownTimeout bounds GracefulShutdown, the last report and error are kept for later callers
+	shutdownTimeout time.Duration
+	shutdownReport  *ShutdownReport
+	shutdownErr     error
+	// registered servers by label, nil once deregistered for stop. Once the listeners
+	// are handed off by a hot restart the registrations belong to the new process.
+	rmu        sync.Mutex
+	registered map[string]*server.ServiceInfo
+	handedOff  bool
+	// updates of servers by label applied to their infos, see UpdateServer
+	updates map[string]ServerUpdate
+}
+
+// New creates an Application with the dependencies set by opts, the others are
+// the defaults of DefaultApp. The loaded config, the xlog loggers and the redacted
+// values are globals of the process, shared by every Application.
+func New(opts ...Option) (*Application, error) {
 	app := &Application{}
+	app.WithOptions(opts...)
+	app.initialize()
+	return app, nil
 }
 
+// DefaultApp creates an Application with the default dependencies: xlog.JupiterLogger,
+// toml config parser, no service registry, signals of the process and flags of os.Args.
 func DefaultApp() *Application {
 	app := &Application{}
+	app.initialize()
 	return app
 }
 
-func (app *Application) intiHooks(hookKeys ...uint32) {
-	app.hooks = make(map[uint32]*xdefer.DeferStack, len(hookKeys))
+// initHooks stop stages run hooks in reverse register order, others in register order
+func (app *Application) initHooks(hookKeys ...uint32) {
+	app.hooks = make(map[uint32]*xhook.Hooks, len(hookKeys))
 	for _, k := range hookKeys {
-		app.hooks[k] = xdefer.NewStack()
+		if k == StageBeforeStop || k == StageAfterStop {
+			app.hooks[k] = xhook.NewStack()
+		} else {
+			app.hooks[k] = xhook.NewQueue()
+		}
 	}
 }
 
-func (app *Application) runHooks(k uint32) {
+// runHooks runs hooks of stage k, every hook is logged with its name and cost
+func (app *Application) runHooks(k uint32) *xhook.Report {
 	hooks, ok := app.hooks[k]
-	if ok {
-		hooks.Clean()
+	if !ok {
+		return &xhook.Report{}
 	}
+	stage := stageNames[k]
+	report := hooks.Run(context.Background())
+	app.lifecycle.observeHooks(stage, report)
+	for _, r := range report.Results {
+		if r.Err != nil {
+			app.logger.Error("run hook", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent(stage), xlog.FieldName(r.Name), xlog.FieldCost(r.Cost), xlog.FieldErr(r.Err))
+			continue
+		}
+		app.logger.Info("run hook", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent(stage), xlog.FieldName(r.Name), xlog.FieldCost(r.Cost))
+	}
+	if report.Aborted {
+		app.logger.Error("hooks aborted", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent(stage), xlog.FieldErr(report.Err()))
+	}
+	return report
 }
 
+// RegisterHooks register funcs of a stage, each func is a hook named after the
+// func with no timeout, a failed hook does not stop the others
 func (app *Application) RegisterHooks(k uint32, fns ...func() error) error {
-	hooks, ok := app.hooks[k]
+	hooks := make([]*xhook.Hook, 0, len(fns))
+	for _, fn := range fns {
+		hooks = append(hooks, xhook.Func(fn))
+	}
+	return app.RegisterHook(k, hooks...)
+}
+
+// RegisterHook register named hooks of a stage
+func (app *Application) RegisterHook(k uint32, hooks ...*xhook.Hook) error {
+	stage, ok := app.hooks[k]
 	if ok {
-		hooks.Push(fns...)
+		stage.Push(hooks...)
 		return nil
 	}
 	return fmt.Errorf("hook stage not found")
@@ -83,143 +181,421 @@ func (app *Application) RegisterHooks(k uint32, fns ...func() error) error {
 
 func (app *Application) initialize() {
 	app.initOnce.Do(func() {
-		app.cycle = NewCycle()
+		app.cycle = NewContextCycle(context.Background(), app.cyclePolicy)
 		app.smu = &sync.RWMutex{}
 		app.servers = make([]server.Server, 0)
 		app.workers = make([]worker.Worker, 0)
-		app.jobs = make(map[string]xjob.Runner)
-		app.logger = JupiterLogger
-		app.configParser = toml.Unmarshal
-		app.disableMap = make(map[Disable]bool)
+		app.jobs = make(map[string]xjob.RunnerE)
+		app.configSources = make(map[string]conf.DataSource)
+		app.registered = make(map[string]*server.ServiceInfo)
+		app.updates = make(map[string]ServerUpdate)
+		// dependencies set by options are kept
+		if app.logger == nil {
+			app.logger = xlog.JupiterLogger
+		}
+		if app.configParser == nil {
+			app.configParser = toml.Unmarshal
+		}
+		if app.disableMap == nil {
+			app.disableMap = make(map[Disable]bool)
+		}
+		if app.metrics == nil {
+			app.metrics = prometheus.NewRegistry()
+		}
+		if app.clock == nil {
+			app.clock = xtime.SystemClock
+		}
+		if app.flags == nil {
+			app.flags = flag.CommandLine()
+		}
+		if app.signalSource == nil {
+			app.signalSource = signals.OS
+		}
+		if app.upgrader == nil {
+			app.upgrader = upgrade.Upgrade
+		}
 
-		app.initHooks(StageBeforeStop, StageAfterStop)
-		app.SetRegistry(registry.Nop{})
+		app.cycle.logger = app.logger
+		app.cycle.clock = app.clock
+		app.lifecycle = newAppMetrics(app)
+		app.exit = os.Exit
+		app.signals = signals.NewRouter(app.signalSource)
+		app.signals.Observe(app.lifecycle.observeSignal)
+		app.initSignals()
+		app.initHooks(StageBeforeStart, StageAfterStart, StageBeforeServe, StageAfterServe, StageBeforeStop, StageAfterStop)
 	})
 }
 
+// startup runs the framework stages:
+// - parse config, watch, version flags
+// - load config
+// - init registry
+// - init governor
 func (app *Application) startup() (err error) {
 	app.startupOnce.Do(func() {
-		err = xgo.SerialUntilError()
+		err = app.runStages(
+			&xstage.Stage{Name: "parseFlags", Run: stageFunc(app.parseFlags)},
+			&xstage.Stage{Name: "loadConfig", Deps: []string{"parseFlags"}, Run: stageFunc(app.loadConfig)},
+			&xstage.Stage{Name: "initRegistry", Deps: []string{"loadConfig"}, Run: stageFunc(app.initRegistry)},
+			&xstage.Stage{Name: "initGovernor", Deps: []string{"loadConfig"}, Run: stageFunc(app.initGovernor)},
+		)
 	})
 	return
 }
 
+// Startup runs fns in call order after the framework stages,
+// each fn is a stage named after the func.
 func (app *Application) Startup(fns ...func() error) error {
+	return app.StartupStages(xstage.Serial(fns...)...)
+}
+
+// StartupStages runs named stages after the framework stages. Stages are resolved
+// by their Deps and run in parallel where possible, undo funcs of finished
+// stages run when a later stage fails. Startup fails with a *conf.ValidationError
+// if any config unmarshalled in stages is invalid.
+func (app *Application) StartupStages(stages ...*xstage.Stage) error {
 	app.initialize()
+	if report := app.runHooks(StageBeforeStart); report.Aborted {
+		return report.Err()
+	}
 	if err := app.startup(); err != nil {
 		return err
 	}
-	return xgo.SerialUntilError(fns...)()
+	if err := app.runStages(stages...); err != nil {
+		return err
+	}
+	// components unmarshal config in stages, their invalid fields fail startup in one report
+	if err := conf.Invalid(); err != nil {
+		app.logger.Error("invalid config", xlog.FieldMod(ecode.ModConfig), xlog.FieldErr(err))
+		return err
+	}
+	if report := app.runHooks(StageAfterStart); report.Aborted {
+		return report.Err()
+	}
+	return nil
+}
+
+func (app *Application) runStages(stages ...*xstage.Stage) error {
+	timed := make([]*xstage.Stage, 0, len(stages))
+	for _, s := range stages {
+		timed = append(timed, app.timeStage(s))
+	}
+	g, err := xstage.New(timed...)
+	if err != nil {
+		return err
+	}
+	if err := g.Run(context.Background()); err != nil {
+		app.logger.Error("startup stage failed", xlog.FieldMod(ecode.ModApp), xlog.FieldErr(err))
+		return err
+	}
+	return nil
+}
+
+// timeStage returns a copy of s whose duration is observed in the lifecycle metrics
+func (app *Application) timeStage(s *xstage.Stage) *xstage.Stage {
+	timed := *s
+	if s.Run != nil {
+		timed.Run = func(ctx context.Context) error {
+			start := app.clock.Now()
+			err := s.Run(ctx)
+			app.lifecycle.observeStage(s.Name, app.clock.Since(start), err)
+			return err
+		}
+	}
+	return &timed
+}
+
+func stageFunc(fn func() error) func(context.Context) error {
+	return func(context.Context) error {
+		return fn()
+	}
 }
 
 func (app *Application) Serve(s ...server.Server) error {
 	app.smu.Lock()
 	defer app.smu.Unlock()
 	app.servers = append(app.servers, s...)
+	for _, srv := range s {
+		app.watchWeight(srv)
+	}
 	return nil
 }
 
+// watchWeight updates the weight of s with jupiter.server.<name>.weight, a negative weight is rejected,
+// see UpdateServer
+func (app *Application) watchWeight(s server.Server) {
+	key := "jupiter.server." + s.Info().Name + ".weight"
+	conf.Validate(func(config *conf.Configuration) error {
+		if value := config.Get(key); value != nil {
+			if weight, err := xcast.ToFloat64E(value); err != nil || weight < 0 {
+				return fmt.Errorf("%s: invalid weight %v", key, value)
+			}
+		}
+		return nil
+	})
+	conf.OnChange(key, func(_, value interface{}) {
+		weight, err := xcast.ToFloat64E(value)
+		if value == nil || err != nil {
+			return
+		}
+		app.logger.Info("update server weight", xlog.FieldMod(ecode.ModApp), xlog.FieldName(s.Info().Name), xlog.Any("to", weight))
+		app.updateServer(s, ServerUpdate{Weight: &weight})
+	})
+}
+
+// Schedule runs w under a supervisor, workers which are not yet a *xsupervisor.Supervisor
+// get the default restart policy.
 func (app *Application) Schedule(w worker.Worker) error {
-	app.workers = append(app.workers, w)
+	s, ok := w.(*xsupervisor.Supervisor)
+	if !ok {
+		s = xsupervisor.DefaultConfig().WithLogger(app.logger).Build(w)
+	}
+	app.workers = append(app.workers, s)
 	return nil
 }
 
+// WorkerStatus returns restart count and last error of every scheduled worker.
+func (app *Application) WorkerStatus() []xsupervisor.Status {
+	status := make([]xsupervisor.Status, 0, len(app.workers))
+	for _, w := range app.workers {
+		if s, ok := w.(*xsupervisor.Supervisor); ok {
+			status = append(status, s.Status())
+		}
+	}
+	return status
+}
+
+// Job registers runner as the job of its GetJobName, see JobE. A runner which is a
+// xjob.RunnerE too is run by RunE.
 func (app *Application) Job(runner xjob.Runner) error {
 	namedJob, ok := runner.(interface{ GetJobName() string })
 	if !ok {
 		return nil
 	}
+	return app.job(namedJob.GetJobName(), xjob.Wrap(runner))
+}
 
-	jobName := namedJob.GetJobName()
-	if flag.Bool("disable-job") {
-		app.logger.Info("jupiter disable job", xlog.FieldName(jobName))
+// JobE registers runner as the job of its GetJobName, it is run by Run before the
+// servers if --job chooses it, with the arguments of --job-args, see xjob.ArgsFrom.
+// A failed job fails Run, see xjob.ExitCode.
+func (app *Application) JobE(runner xjob.RunnerE) error {
+	namedJob, ok := runner.(interface{ GetJobName() string })
+	if !ok {
 		return nil
 	}
+	return app.job(namedJob.GetJobName(), runner)
+}
 
-	// start job by name
-	jobFlag := flag.String("job")
-	if jobFlag == "" {
-		app.logger.Error("jupiter jobs flag name empty", xlog.FieldName(jobName))
+func (app *Application) job(jobName string, runner xjob.RunnerE) error {
+	if app.flags.Bool("disable-job") {
+		app.logger.Info("jupiter disable job", xlog.FieldName(jobName))
 		return nil
 	}
 
-	if jobName != jobFlag {
-		app.logger.Info("jupiter disable jobs", xlog.FieldName(jobName))
-		return nil
-	}
 	app.logger.Info("jupiter register job", xlog.FieldName(jobName))
+	app.smu.Lock()
 	app.jobs[jobName] = runner
+	app.smu.Unlock()
 	return nil
 }
 
-func (app *Application) SetRegistry(reg registry.Registry) {
-	app.registerer = reg
+// SetRegistry sets the service registries, see WithRegistry
+func (app *Application) SetRegistry(regs ...registry.Registry) {
+	app.registries = regs
 }
 
+// MetricsRegistry returns the prometheus registry of the framework metrics, see WithMetricsRegistry
+func (app *Application) MetricsRegistry() *prometheus.Registry {
+	app.initialize()
+	return app.metrics
+}
+
+// Run serves servers and the scheduled workers until app stops. With --job it runs
+// the chosen jobs instead and stops app once they return, see RunAndExit.
 func (app *Application) Run(servers ...server.Server) error {
 	app.smu.Lock()
 	app.servers = append(app.servers, servers...)
 	app.smu.Unlock()
 
+	app.waitUpgrade()
 	app.waitSignals()
-	defer app.Clean()
+	defer app.clean()
+
+	if report := app.runHooks(StageBeforeServe); report.Aborted {
+		return report.Err()
+	}
+	if app.flags.Bool("list-jobs") {
+		app.listJobs(os.Stdout)
+		return nil
+	}
+	// a job run stops app once the jobs return, servers and workers are not started
+	if app.flags.String("job") != "" {
+		err := app.startJobs()
+		_ = app.GracefulStop(context.Background())
+		if err != nil {
+			app.logger.Error("jupiter job failed", xlog.FieldMod(ecode.ModApp), xlog.FieldErr(err))
+			return err
+		}
+		app.logger.Info("shutdown jupiter, bye!", xlog.FieldMod(ecode.ModApp))
+		return nil
+	}
+	app.startServers()
+	app.startWorkers()
+	app.runHooks(StageAfterServe)
+
+	// started by a hot restart, the old process stops once we report ready
+	if err := upgrade.Ready(); err != nil {
+		app.logger.Error("hot restart ready", xlog.FieldMod(ecode.ModApp), xlog.FieldErr(err))
+	}
+
+	// cancelled once app stops, or by a failed server or worker under FailFast, which
+	// stops the others gracefully. A stop in progress is waited for.
+	<-app.cycle.Context().Done()
+	_ = app.GracefulStop(context.Background())
+	if err := app.cycle.failures(); err != nil {
+		app.logger.Error("jupiter shutdown with error", xlog.FieldMod(ecode.ModApp), xlog.FieldErr(err))
+		return err
+	}
+	app.logger.Info("shutdown jupiter, bye!", xlog.FieldMod(ecode.ModApp))
+	return nil
+}
 
-	app.startJobs()
-	app.cycle.Run(app.s)
+// RunAndExit runs app like Run and exits the process with the exit code of the error
+// of Run, see xjob.ExitCode, such as the code of a failed job of --job. It is meant
+// to end a main function.
+func (app *Application) RunAndExit(servers ...server.Server) {
+	app.exit(xjob.ExitCode(app.Run(servers...)))
+}
 
+// SetUpgradeSignal enables hot restart: on sig the listeners of servers implementing
+// server.ListenerExporter are handed to a new process of the binary, and app
+// stops gracefully once the new process is ready. sig replaces the action mapped to
+// it, such as the level cycling of signals.SignalLevel (SIGUSR2), which is logged.
+func (app *Application) SetUpgradeSignal(sig os.Signal) {
+	app.upgradeSignal = sig
+}
+
+// waitUpgrade maps the upgrade signal to hot restart, it replaces the default action of the signal
+func (app *Application) waitUpgrade() {
+	if app.upgradeSignal == nil {
+		return
+	}
+	if app.signals.Handles(app.upgradeSignal) {
+		app.logger.Warn("upgrade signal replaces its action", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent(app.upgradeSignal.String()))
+	}
+	app.signals.Handle(app.upgradeSignal, func(os.Signal) {
+		if err := app.upgrade(); err != nil {
+			app.logger.Error("hot restart", xlog.FieldMod(ecode.ModApp), xlog.FieldErr(err))
+			return
+		}
+		_ = app.GracefulStop(context.TODO())
+	})
+}
+
+func (app *Application) upgrade() error {
+	listeners := make([]net.Listener, 0)
+	app.smu.RLock()
+	for _, s := range app.servers {
+		if e, ok := s.(server.ListenerExporter); ok {
+			listeners = append(listeners, e.Listener())
+		}
+	}
+	app.smu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), upgradeTimeout)
+	defer cancel()
+	app.logger.Info("hot restart begin", xlog.FieldMod(ecode.ModApp), xlog.Int("listeners", len(listeners)))
+	proc, err := app.upgrader(ctx, listeners...)
+	if err != nil {
+		return err
+	}
+	app.rmu.Lock()
+	app.handedOff = true
+	app.rmu.Unlock()
+	app.logger.Info("hot restart child ready", xlog.FieldMod(ecode.ModApp), xlog.Int("pid", proc.Pid))
+	return nil
 }
 
 func (app *Application) clean() {
+	app.signals.Stop()
+	for _, ds := range app.configSources {
+		_ = ds.Close()
+	}
 	_ = xlog2.DefaultLogger.Flush()
 	_ = xlog.JupiterLogger.Flush()
 }
 
 func (app *Application) Stop() (err error) {
 	app.stopOnce.Do(func() {
-		app.runHooks(StageBeforeStop)
+		err = multierr.Append(err, app.runHooks(StageBeforeStop).Err())
 
-		if app.registerer != nil {
-			err = app.registerer.Close()
-			if err != nil {
-				app.logger.Error("stop register close err", xlog.FieldMod(ecode.ModApp), xlog.FieldErr(err))
-			}
-		}
+		err = multierr.Append(err, app.deregister(context.Background()))
+		app.cycle.Cancel()
 		app.smu.RLock()
 		for _, s := range app.servers {
 			func(s server.Server) {
-				app.cycle.Run(s.Stop)
+				app.cycle.Run("stop "+s.Info().Label(), func() error {
+					start := app.clock.Now()
+					err := s.Stop()
+					app.lifecycle.observeStop(StopResult{Name: s.Info().Label(), Cost: app.clock.Since(start), Forced: true, Err: err})
+					return err
+				})
 			}(s)
 		}
 		app.smu.RUnlock()
 
 		for _, w := range app.workers {
 			func(w worker.Worker) {
-				app.cycle.Run(w.Stop)
+				app.cycle.Run("stop "+workerName(w), w.Stop)
 			}(w)
 		}
 		<-app.cycle.Done()
-		app.runHooks(StageAfterStop)
+		err = multierr.Append(err, app.runHooks(StageAfterStop).Err())
 		app.cycle.Close()
 	})
 	return
 }
 
-func (app *Application) GracefulStop(ctx context.Context)(err error) {
+// GracefulStop application after necessary cleanup, see GracefulShutdown
+func (app *Application) GracefulStop(ctx context.Context) error {
+	_, err := app.GracefulShutdown(ctx)
+	return err
+}
+
+// SetShutdownTimeout set the budget of GracefulShutdown (default 30s)
+func (app *Application) SetShutdownTimeout(timeout time.Duration) {
+	app.shutdownTimeout = timeout
+}
+
+// GracefulShutdown stops servers gracefully and stops workers within the shutdown
+// budget or the ctx deadline, whichever comes first. Servers which miss the
+// deadline are forced through Stop. The context of the cycle tasks is cancelled as
+// the shutdown begins, its Deadline is the one of the shutdown. The report lists how every server and worker stopped, it is
+// logged and returned to every caller.
+func (app *Application) GracefulShutdown(ctx context.Context) (report *ShutdownReport, err error) {
 	app.stopOnce.Do(func() {
-		app.runHooks(StageBeforeStop)
+		err = multierr.Append(err, app.runHooks(StageBeforeStop).Err())
 
-		if app.registerer != nil {
-			err = app.registerer.Close()
-			if err != nil {
-				app.logger.Error("stop register close err", xlog.FieldMod(ecode.ModApp), xlog.FieldErr(err))
-			}
+		err = multierr.Append(err, app.deregister(ctx))
+
+		budget := app.shutdownTimeout
+		if budget <= 0 {
+			budget = defaultShutdownTimeout
 		}
+		ctx, cancel := context.WithTimeout(ctx, budget)
+		defer cancel()
+		// tasks of the cycle are told first, they and the stops below are bounded by ctx
+		app.cycle.CancelBy(ctx)
+
+		start := app.clock.Now()
+		collector := &reportCollector{report: &ShutdownReport{Budget: budget}}
 		//stop servers
 		app.smu.RLock()
 		for _, s := range app.servers {
 			func(s server.Server) {
-				app.cycle.Run(func() error {
-					return s.GracefulStop(ctx)
+				app.cycle.Run("stop "+s.Info().Label(), func() error {
+					collector.addServer(stopServer(ctx, app.clock, s))
+					return nil
 				})
 			}(s)
 		}
@@ -228,47 +604,86 @@ func (app *Application) GracefulStop(ctx context.Context)(err error) {
 		//stop workers
 		for _, w := range app.workers {
 			func(w worker.Worker) {
-				app.cycle.Run(w.Stop)
+				app.cycle.Run("stop "+workerName(w), func() error {
+					collector.addWorker(stopWorker(ctx, app.clock, w))
+					return nil
+				})
 			}(w)
 		}
 		<-app.cycle.Done()
-		app.runHooks(StageAfterStop)
+		collector.report.Cost = app.clock.Since(start)
+		app.shutdownReport = collector.report
+		app.logShutdownReport(app.shutdownReport)
+		err = multierr.Append(err, app.shutdownReport.Err())
+
+		err = multierr.Append(err, app.runHooks(StageAfterStop).Err())
 		app.cycle.Close()
+		app.shutdownErr = err
 	})
-	return err
+	return app.shutdownReport, app.shutdownErr
+}
+
+func (app *Application) logShutdownReport(report *ShutdownReport) {
+	for _, s := range report.Servers {
+		app.lifecycle.observeStop(s)
+		app.logger.Info("server stopped", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent("stop"), xlog.FieldAddr(s.Name), xlog.FieldCost(s.Cost), xlog.Any("forced", s.Forced), xlog.FieldErr(s.Err))
+	}
+	for _, w := range report.Workers {
+		app.logger.Info("worker stopped", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent("stop"), xlog.FieldName(w.Name), xlog.FieldCost(w.Cost), xlog.FieldErr(w.Err))
+	}
+	app.logger.Info("graceful shutdown", xlog.FieldMod(ecode.ModApp), xlog.Duration("budget", report.Budget), xlog.FieldCost(report.Cost), xlog.FieldErr(report.Err()))
 }
 func (app *Application) waitSignals() {
 	app.logger.Info("init listen signal", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent("init"))
-	Shutdown(func(grace bool) {
+	app.signals.HandleShutdown(func(grace bool) {
 		if grace {
 			app.GracefulStop(context.TODO())
 		} else {
 			app.Stop()
 		}
 	})
+	app.signals.Start()
 }
 
-func (app *Application)parseFlags()error  {
-	if app.isDisable(jupiter.DisableParserFlag) {
+func (app *Application) parseFlags() error {
+	if app.isDisable(DisableParserFlag) {
 		return nil
 	}
-	flag.Register(&flag.StringFlag{
-		Name: "config",
-		Usage: "--config",
-		EnvVar: "JUPITER_CONFIG",
+	app.flags.Register(&flag.StringFlag{
+		Name:    "config",
+		Usage:   "--config",
+		EnvVar:  "JUPITER_CONFIG",
 		Default: "",
 		Action: func(name string, flagSet *flag.FlagSet) {
 
 		},
 	})
-	flag.Register(&flag.BoolFlag{
+	app.flags.Register(&flag.StringFlag{
+		Name:    "config-cache",
+		Usage:   "--config-cache, dir of the cached copies of remote config",
+		EnvVar:  "JUPITER_CONFIG_CACHE",
+		Default: filepath.Join(os.TempDir(), "jupiter-config-cache"),
+	})
+	app.flags.Register(&flag.StringFlag{
+		Name:   "secret-key-file",
+		Usage:  "--secret-key-file, key file to decrypt ENC(...) config values",
+		EnvVar: "JUPITER_SECRET_KEY_FILE",
+	})
+	app.flags.Register(&flag.StringSliceFlag{
+		Name:   "set",
+		Usage:  "--set key=value, override a config key, repeatable, separated by comma in JUPITER_CONFIG_SET",
+		EnvVar: "JUPITER_CONFIG_SET",
+	})
+	app.flags.Register(&flag.BoolFlag{
 		Name:    "watch",
 		Usage:   "--watch, watch config change event",
 		Default: false,
 		EnvVar:  "JUPITER_CONFIG_WATCH",
 	})
 
-	flag.Register(&flag.BoolFlag{
+	app.flags.Register(xjob.Flags()...)
+
+	app.flags.Register(&flag.BoolFlag{
 		Name:    "version",
 		Usage:   "--version, print version",
 		Default: false,
@@ -277,38 +692,346 @@ func (app *Application)parseFlags()error  {
 			os.Exit(0)
 		},
 	})
-	return flag.Parse()
+	return app.flags.Parse()
 
 }
 
-func (app *Application)loadConfig()error  {
+// loadConfig loads config layers, a later layer overrides the former ones:
+// - defaults set by conf.SetDefault
+// - sources of --config separated by comma, chosen by scheme such as file://, https://
+//   and kv://, parsed by extension or the config parser
+// - environment variables of the loaded keys and of the fields unmarshalled by
+//   conf.UnmarshalKey, such as JUPITER_LOGGER_DEFAULT_LEVEL
+// - overrides of --set, or of JUPITER_CONFIG_SET separated by comma
+// Sources are reloaded on change with --watch, a remote source unreachable at startup
+// is read from its cached copy in --config-cache.
+func (app *Application) loadConfig() error {
+	if app.isDisable(DisableLoadConfig) {
+		app.logger.Info("load config disable", xlog.FieldMod(ecode.ModConfig))
+		return nil
+	}
+	if err := app.initSecrets(); err != nil {
+		return err
+	}
+	defer app.redactSecrets()
+
+	for _, addr := range strings.Split(app.flags.String("config"), ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		unmarshal, ok := conf.UnmarshallerFor(manager.Path(addr))
+		if !ok {
+			unmarshal = app.configParser
+		}
+		ds, ok := app.configSources[addr]
+		if !ok {
+			var err error
+			if ds, err = manager.NewDataSource(addr, app.flags.Bool("watch")); err != nil {
+				return fmt.Errorf("config %s: %w", addr, err)
+			}
+			if manager.Scheme(addr) != manager.DefaultScheme {
+				ds = manager.Cache(ds, manager.CachePath(app.flags.String("config-cache"), addr))
+			}
+			app.configSources[addr] = ds
+			go app.watchConfig(addr, ds, unmarshal)
+		}
+		if err := app.loadConfigLayer(addr, ds, unmarshal); err != nil {
+			return err
+		}
+		app.logger.Info("load config", xlog.FieldMod(ecode.ModConfig), xlog.FieldAddr(addr))
+	}
+	if err := conf.LoadEnv(os.LookupEnv); err != nil {
+		return err
+	}
+	if err := conf.LoadFlags(app.flags.StringSlice("set")); err != nil {
+		return err
+	}
+	return nil
+}
 
+func (app *Application) loadConfigLayer(path string, ds conf.DataSource, unmarshal conf.Unmarshaller) error {
+	content, err := ds.ReadConfig()
+	if err != nil {
+		return fmt.Errorf("read config %s: %w", path, err)
+	}
+	if err := conf.LoadLayer(path, conf.PriorityFile, content, unmarshal); err != nil {
+		return err
+	}
+	// keys new to the layer are looked up in the environment
+	return conf.LoadEnv(os.LookupEnv)
 }
 
-var shutdownSignals = []os.Signal{syscall.SIGQUIT, os.Interrupt}
+// watchConfig reloads the layer of path on every change of ds, a reload failed to
+// parse or validate is rejected and the live config keeps untouched
+func (app *Application) watchConfig(path string, ds conf.DataSource, unmarshal conf.Unmarshaller) {
+	for range ds.IsConfigChanged() {
+		// new ENC(...) values may be encrypted by a rotated key
+		if err := app.initSecrets(); err != nil {
+			app.logger.Error("reload config rejected", xlog.FieldMod(ecode.ModConfig), xlog.FieldAddr(path), xlog.FieldErr(err))
+			continue
+		}
+		err := app.loadConfigLayer(path, ds, unmarshal)
+		app.redactSecrets()
+		if err != nil {
+			app.logger.Error("reload config rejected", xlog.FieldMod(ecode.ModConfig), xlog.FieldAddr(path), xlog.FieldErr(err))
+			continue
+		}
+		app.logger.Info("reload config", xlog.FieldMod(ecode.ModConfig), xlog.FieldAddr(path))
+	}
+}
 
-func Shutdown(stop func(grace bool)) {
-	sig := make(chan os.Signal, 2)
-	signal.Notify(
-		sig,
-		shutdownSignals, ...
-	)
-	go func() {
-		s := <-sig
-		go stop(s != syscall.SIGQUIT)
-		<-sig
-		os.Exit(128 + int(s.(syscall.Signal)))
-	}()
+// ConfigProvenance returns the layer each effective config key comes from,
+// one of default, a config file path, env and flag.
+func (app *Application) ConfigProvenance() map[string]string {
+	return conf.Provenances()
 }
 
-func (app *Application) initGovernor() error {
-	if app.isDisable(DisableDefaultGovernor) {
-		app.logger.Info("defualt governor disable", xlog.FieldMod(ecode.ModApp))
-		return nil
+const upgradeTimeout = 30 * time.Second
+
+// startServers serves every server as a task of the cycle, a server is registered
+// once it is ready, see server.ReadyNotifier, and unregistered when Serve returns
+func (app *Application) startServers() {
+	app.smu.RLock()
+	defer app.smu.RUnlock()
+	for _, s := range app.servers {
+		s := s
+		app.cycle.Go("serve "+s.Info().Label(), func(context.Context) (err error) {
+			app.logger.Info("start server", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent("init"), xlog.FieldName(s.Info().Name), xlog.FieldAddr(s.Info().Label()), xlog.Any("scheme", s.Info().Scheme))
+			defer app.logger.Info("exit server", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent("exit"), xlog.FieldName(s.Info().Name), xlog.FieldErr(err), xlog.FieldAddr(s.Info().Label()))
+			serving := make(chan error, 1)
+			go func() {
+				start := app.clock.Now()
+				// a panic of Serve stops the app as an error of the server
+				err := app.cycle.protect(s.Info().Label(), s.Serve)
+				app.lifecycle.observeServe(s.Info().Label(), app.clock.Since(start), err)
+				serving <- err
+			}()
+			if r, ok := s.(server.ReadyNotifier); ok {
+				select {
+				case <-r.Ready():
+				case err = <-serving:
+					return
+				}
+			}
+			app.register(s)
+			err = <-serving
+			app.unregister(s.Info())
+			return
+		})
 	}
+}
+
+// initRegistry fans the registries out with the config of jupiter.registry.compound
+func (app *Application) initRegistry() error {
+	app.registerer = compound.StdConfig("compound").WithLogger(app.logger).Build(app.registries...)
+	return nil
+}
 
-	config := governor.StdConfig("governor")
-	if !config.Enable {
+// register registers the info of s, a failed registration is retried by the registry
+func (app *Application) register(s server.Server) {
+	app.rmu.Lock()
+	defer app.rmu.Unlock()
+	if app.registered == nil || app.registerer == nil {
+		return
+	}
+	info := app.serviceInfo(s)
+	app.registered[info.Label()] = info
+	if err := app.registerer.RegisterService(context.Background(), info); err != nil {
+		app.logger.Error("register service", xlog.FieldMod(ecode.ModApp), xlog.FieldAddr(info.Label()), xlog.FieldErr(err))
+		return
+	}
+	app.logger.Info("register service", xlog.FieldMod(ecode.ModApp), xlog.FieldAddr(info.Label()))
+}
+
+// unregister unregisters info if it is registered
+func (app *Application) unregister(info *server.ServiceInfo) {
+	app.rmu.Lock()
+	defer app.rmu.Unlock()
+	if _, ok := app.registered[info.Label()]; !ok {
+		return
+	}
+	delete(app.registered, info.Label())
+	if err := app.registerer.UnregisterService(context.Background(), info); err != nil {
+		app.logger.Error("unregister service", xlog.FieldMod(ecode.ModApp), xlog.FieldAddr(info.Label()), xlog.FieldErr(err))
+	}
+}
+
+// deregister unregisters every registered server and closes the registries, so that
+// servers leave discovery before they stop. Servers ready later are not registered.
+// After a hot restart the new process serves the same labels, they are kept.
+func (app *Application) deregister(ctx context.Context) (err error) {
+	app.rmu.Lock()
+	defer app.rmu.Unlock()
+	registered := app.registered
+	app.registered = nil
+	if app.registerer == nil {
+		return nil
+	}
+	if app.handedOff {
+		app.registerer.Detach()
+		app.logger.Info("services kept for the new process", xlog.FieldMod(ecode.ModApp), xlog.Int("services", len(registered)))
 		return nil
 	}
+	for label, info := range registered {
+		if uerr := app.registerer.UnregisterService(ctx, info); uerr != nil {
+			err = multierr.Append(err, uerr)
+			app.logger.Error("unregister service", xlog.FieldMod(ecode.ModApp), xlog.FieldAddr(label), xlog.FieldErr(uerr))
+			continue
+		}
+		app.logger.Info("unregister service", xlog.FieldMod(ecode.ModApp), xlog.FieldAddr(label))
+	}
+	if cerr := app.registerer.Close(); cerr != nil {
+		err = multierr.Append(err, cerr)
+		app.logger.Error("stop register close err", xlog.FieldMod(ecode.ModApp), xlog.FieldErr(cerr))
+	}
+	return err
+}
+
+// startWorkers runs every worker as a task of the cycle, a worker.ContextWorker is
+// told to return by the cancel of the cycle context
+func (app *Application) startWorkers() {
+	for _, w := range app.workers {
+		w := w
+		app.cycle.Go("worker "+workerName(w), func(ctx context.Context) error {
+			if cw, ok := w.(worker.ContextWorker); ok {
+				return cw.RunContext(ctx)
+			}
+			return w.Run()
+		})
+	}
+}
+
+// scheduledJob is a job chosen by --job with the arguments of its run
+type scheduledJob struct {
+	name   string
+	runner xjob.RunnerE
+	args   xjob.Args
+}
+
+// scheduleJobs returns the jobs of --job with their arguments of --job-args, an unknown
+// job or an invalid argument is an error of xjob.ExitUsage
+func (app *Application) scheduleJobs() ([]scheduledJob, error) {
+	app.smu.RLock()
+	defer app.smu.RUnlock()
+	var (
+		jobs    []scheduledJob
+		unknown []string
+	)
+	pairs := app.flags.StringSlice("job-args")
+	for _, name := range strings.Split(app.flags.String("job"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		runner, ok := app.jobs[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		jobs = append(jobs, scheduledJob{name: name, runner: runner})
+	}
+	if len(unknown) > 0 {
+		return nil, xjob.Exit(xjob.ExitUsage, fmt.Errorf("unknown job %s, see --list-jobs", strings.Join(unknown, ", ")))
+	}
+	for i, job := range jobs {
+		_, schema := xjob.Describe(job.runner)
+		args, err := xjob.ParseArgs(pairs, schema)
+		if err != nil {
+			return nil, xjob.Exit(xjob.ExitUsage, fmt.Errorf("job %s: %w", job.name, err))
+		}
+		jobs[i].args = args
+	}
+	return jobs, nil
+}
+
+// startJobs runs the jobs of --job in sequence, or in parallel with --job-parallel,
+// with a context derived from the one of the cycle, which is cancelled once app is
+// told to stop such as by SIGINT. The first failed job stops the others: a later job
+// in sequence is not run, the context of the jobs in parallel is cancelled. The errors
+// of the failed jobs are returned, jobs cancelled by a failure are left out.
+func (app *Application) startJobs() error {
+	jobs, err := app.scheduleJobs()
+	if err != nil || len(jobs) == 0 {
+		return err
+	}
+	ctx, cancel := context.WithCancel(app.cycle.Context())
+	defer cancel()
+	var (
+		mu     sync.Mutex
+		errs   error
+		failed int
+	)
+	run := func(job scheduledJob) {
+		err := app.runJob(ctx, job)
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err == nil:
+		case errors.Is(err, context.Canceled) && app.cycle.Context().Err() == nil:
+			// cancelled by a failed job
+		default:
+			errs = multierr.Append(errs, err)
+			failed++
+			cancel()
+		}
+	}
+	if app.flags.Bool("job-parallel") {
+		fns := make([]func(), 0, len(jobs))
+		for _, job := range jobs {
+			job := job
+			fns = append(fns, func() { run(job) })
+		}
+		xgo.Parallel(fns...)()
+	} else {
+		for _, job := range jobs {
+			if run(job); ctx.Err() != nil {
+				break
+			}
+		}
+	}
+	app.logger.Info("job run summary", xlog.FieldMod(ecode.ModApp), xlog.Int("jobs", len(jobs)), xlog.Int("failed", failed), xlog.Int("exitCode", xjob.ExitCode(errs)))
+	return errs
+}
+
+// listJobs writes the registered jobs sorted by name to w, see xjob.Usage
+func (app *Application) listJobs(w io.Writer) {
+	for _, name := range app.jobNames() {
+		app.smu.RLock()
+		runner := app.jobs[name]
+		app.smu.RUnlock()
+		xjob.Usage(w, name, runner)
+	}
+}
+
+// runJob runs job with ctx and a new run ID, a panic of job is a failure of the run
+func (app *Application) runJob(ctx context.Context, job scheduledJob) error {
+	name, runner := job.name, job.runner
+	start := app.clock.Now()
+	runID := xjob.NewRunID(start)
+	ctx = xjob.WithArgs(xjob.WithRunID(ctx, runID), job.args)
+	fields := []xlog.Field{xlog.FieldMod(ecode.ModApp), xlog.FieldName(name), xlog.String("runId", runID)}
+	app.logger.Info("job run begin", fields...)
+
+	err := app.cycle.protect("job "+name, func() error {
+		return runner.RunE(ctx)
+	})
+	cost := app.clock.Since(start)
+	res := result(err)
+	var pe *PanicError
+	switch {
+	case errors.As(err, &pe):
+		res = resultPanic
+	case errors.Is(err, context.Canceled):
+		res = resultCancel
+	}
+	app.lifecycle.observeJob(name, cost, res)
+
+	fields = append(fields, xlog.FieldCost(cost), xlog.String("result", res), xlog.Int("exitCode", xjob.ExitCode(err)))
+	if err != nil {
+		app.logger.Error("job run end", append(fields, xlog.FieldErr(err))...)
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+	app.logger.Info("job run end", fields...)
+	return nil
 }
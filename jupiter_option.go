@@ -0,0 +1,123 @@
+package copy
+
+import (
+	"context"
+	"copy/pkg/conf"
+	"copy/pkg/flag"
+	"copy/pkg/registry"
+	"copy/pkg/signals"
+	"copy/pkg/util/xtime"
+	"copy/pkg/xlog"
+	"net"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Option sets a dependency of Application, see New.
+type Option func(a *Application)
+
+// Disable is a framework startup stage which can be disabled by WithDisable.
+type Disable int
+
+const (
+	// DisableParserFlag skips parsing flags
+	DisableParserFlag Disable = 1
+	// DisableLoadConfig skips loading config sources
+	DisableLoadConfig Disable = 2
+	// DisableDefaultGovernor skips the default governor server
+	DisableDefaultGovernor Disable = 3
+)
+
+// WithOptions applies options to a, it must be called before a starts up.
+func (a *Application) WithOptions(options ...Option) {
+	for _, option := range options {
+		option(a)
+	}
+}
+
+// WithLogger sets the logger of the framework, xlog.JupiterLogger by default.
+func WithLogger(logger *xlog.Logger) Option {
+	return func(a *Application) {
+		a.logger = logger
+	}
+}
+
+// WithConfigParser sets the parser of config sources whose format is not told by
+// the extension, toml.Unmarshal by default.
+func WithConfigParser(unmarshaller conf.Unmarshaller) Option {
+	return func(a *Application) {
+		a.configParser = unmarshaller
+	}
+}
+
+// WithRegistry sets the service registries servers are registered in once they are
+// ready, a registration is fanned out to every registry. No registry by default.
+func WithRegistry(regs ...registry.Registry) Option {
+	return func(a *Application) {
+		a.registries = regs
+	}
+}
+
+// WithMetricsRegistry sets the prometheus registry of the framework metrics,
+// a new registry of every Application by default.
+func WithMetricsRegistry(reg *prometheus.Registry) Option {
+	return func(a *Application) {
+		a.metrics = reg
+	}
+}
+
+// WithClock sets the clock timing shutdown and naming dumps, xtime.SystemClock by default.
+func WithClock(clock xtime.Clock) Option {
+	return func(a *Application) {
+		a.clock = clock
+	}
+}
+
+// WithSignalSource sets where signals come from, signals.OS by default.
+func WithSignalSource(source signals.Source) Option {
+	return func(a *Application) {
+		a.signalSource = source
+	}
+}
+
+// WithFlagSet sets the flagset framework flags are registered in and parsed by,
+// flag.CommandLine() by default.
+func WithFlagSet(fs *flag.FlagSet) Option {
+	return func(a *Application) {
+		a.flags = fs
+	}
+}
+
+// WithDisable disables framework startup stages.
+func WithDisable(ds ...Disable) Option {
+	return func(a *Application) {
+		if a.disableMap == nil {
+			a.disableMap = make(map[Disable]bool)
+		}
+		for _, d := range ds {
+			a.disableMap[d] = true
+		}
+	}
+}
+
+func (a *Application) isDisable(d Disable) bool {
+	return a.disableMap[d]
+}
+
+// WithUpgrader sets how the listeners of servers are handed to a new process on the
+// upgrade signal, see SetUpgradeSignal, upgrade.Upgrade by default.
+func WithUpgrader(upgrader func(ctx context.Context, listeners ...net.Listener) (*os.Process, error)) Option {
+	return func(a *Application) {
+		a.upgrader = upgrader
+	}
+}
+
+// WithCyclePolicy sets whether a failed server or worker cancels the others and stops
+// the app, FailFast by default. With KeepGoing the app runs until it is stopped and
+// Run returns the errors of every failed task.
+func WithCyclePolicy(policy CyclePolicy) Option {
+	return func(a *Application) {
+		a.cyclePolicy = policy
+	}
+}
@@ -0,0 +1,639 @@
+package copy
+
+import (
+	"context"
+	"copy/constant"
+	"copy/pkg/conf"
+	"copy/pkg/flag"
+	"copy/pkg/registry/memory"
+	"copy/pkg/secret"
+	"copy/pkg/server"
+	"copy/pkg/worker/xjob"
+	"copy/pkg/worker/xsupervisor"
+	"copy/pkg/xlog"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type testServer struct {
+	name    string
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newTestServer(name string) *testServer {
+	return &testServer{name: name, stopped: make(chan struct{})}
+}
+
+func (s *testServer) Serve() error {
+	<-s.stopped
+	return nil
+}
+
+func (s *testServer) Stop() error {
+	s.once.Do(func() { close(s.stopped) })
+	return nil
+}
+
+func (s *testServer) GracefulStop(ctx context.Context) error {
+	return s.Stop()
+}
+
+func (s *testServer) Info() *server.ServiceInfo {
+	return &server.ServiceInfo{Name: s.name, Scheme: "test", Address: s.name}
+}
+
+// testSignals is a signals.Source fed by send
+type testSignals struct {
+	mu sync.Mutex
+	c  chan<- os.Signal
+}
+
+func (s *testSignals) Notify(c chan<- os.Signal, _ ...os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c = c
+}
+
+func (s *testSignals) Stop(chan<- os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c = nil
+}
+
+func (s *testSignals) send(sig os.Signal) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.c == nil {
+		return false
+	}
+	s.c <- sig
+	return true
+}
+
+func TestNew_Dependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jupiter")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "a.toml"), []byte("[app.a]\nname = \"a\""), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "b.toml"), []byte("[app.b]\nname = \"b\""), 0644))
+
+	newApp := func(name string) (*Application, *testSignals) {
+		source := &testSignals{}
+		app, err := New(
+			WithFlagSet(flag.NewFlagSet(name, []string{"--config=" + filepath.Join(dir, name+".toml")})),
+			WithSignalSource(source),
+			WithDisable(DisableDefaultGovernor),
+		)
+		assert.Nil(t, err)
+		assert.Nil(t, app.Startup())
+		return app, source
+	}
+	a, sourceA := newApp("a")
+	b, sourceB := newApp("b")
+	assert.Equal(t, filepath.Join(dir, "a.toml"), a.flags.String("config"))
+	assert.Equal(t, filepath.Join(dir, "b.toml"), b.flags.String("config"))
+	assert.NotSame(t, a.MetricsRegistry(), b.MetricsRegistry())
+
+	errs := make(chan error, 2)
+	go func() { errs <- a.Run(newTestServer("a")) }()
+	go func() { errs <- b.Run(newTestServer("b")) }()
+
+	send := func(source *testSignals) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !source.send(syscall.SIGINT) {
+			if time.Now().After(deadline) {
+				t.Fatal("signals not listened")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	// only a stops on its signal
+	send(sourceA)
+	select {
+	case err := <-errs:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("a not stopped")
+	}
+	assert.NotNil(t, a.shutdownReport)
+	assert.Nil(t, b.shutdownReport)
+
+	send(sourceB)
+	select {
+	case err := <-errs:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("b not stopped")
+	}
+}
+
+func TestApplication_Governor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jupiter")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "governor.toml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("[jupiter.server.governor]\nhost = \"127.0.0.1\"\nport = 0"), 0644))
+
+	source := &testSignals{}
+	core, logs := observer.New(zapcore.InfoLevel)
+	app, err := New(
+		WithFlagSet(flag.NewFlagSet("governor", []string{"--config=" + path})),
+		WithSignalSource(source),
+		WithLogger(xlog.Config{Name: "governor.log", Core: core}.Build()),
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, app.Startup())
+	governor := app.Governor()
+	if !assert.NotNil(t, governor) {
+		return
+	}
+	// the free port is told by a warning
+	warns := logs.FilterField(xlog.FieldAddr(governor.Info().Label())).AllUntimed()
+	if assert.NotEmpty(t, warns) {
+		assert.Equal(t, zapcore.WarnLevel, warns[0].Level)
+	}
+	errs := make(chan error, 1)
+	go func() { errs <- app.Run(newTestServer("a")) }()
+
+	get := func(path string, v interface{}) {
+		var resp *http.Response
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			resp, err = http.Get("http://" + governor.Info().Address + path)
+			if err == nil || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		if v != nil {
+			assert.Nil(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+	}
+	var infos []server.ServiceInfo
+	get("/servers", &infos)
+	kinds := make(map[string]constant.ServiceKind)
+	for _, info := range infos {
+		kinds[info.Address] = info.Kind
+	}
+	assert.Equal(t, constant.ServiceGovernor, kinds[governor.Info().Address])
+	assert.Contains(t, kinds, "a")
+	var jobs []string
+	get("/jobs", &jobs)
+	assert.Empty(t, jobs)
+	get("/workers", nil)
+	var tasks []TaskStatus
+	get("/tasks", &tasks)
+	if assert.NotEmpty(t, tasks) {
+		assert.Equal(t, "serve "+governor.Info().Label(), tasks[0].Name)
+	}
+	get("/metrics", nil)
+	// connections dialed but never used hold graceful stop up to 5s
+	http.DefaultClient.CloseIdleConnections()
+
+	for !source.send(syscall.SIGINT) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-errs:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("app not stopped")
+	}
+}
+
+func TestApplication_UpdateServer(t *testing.T) {
+	reg := memory.New()
+	source := &testSignals{}
+	app, err := New(
+		WithFlagSet(flag.NewFlagSet("traffic", nil)),
+		WithSignalSource(source),
+		WithRegistry(reg),
+		WithDisable(DisableDefaultGovernor),
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, app.Startup())
+	events, err := reg.Watch(context.Background(), "a")
+	assert.Nil(t, err)
+
+	errs := make(chan error, 1)
+	go func() { errs <- app.Run(newTestServer("a")) }()
+	next := func() memory.Event {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no registry event")
+		}
+		return memory.Event{}
+	}
+	assert.Equal(t, memory.EventAdd, next().Type)
+
+	enable, weight := false, float64(10)
+	info, err := app.UpdateServer("test://a", ServerUpdate{Enable: &enable, Weight: &weight})
+	assert.Nil(t, err)
+	assert.False(t, info.Enable)
+	event := next()
+	assert.Equal(t, memory.EventUpdate, event.Type)
+	assert.False(t, event.Service.Enable)
+	assert.Equal(t, float64(10), event.Service.Weight)
+	assert.False(t, app.serviceInfos()[0].Enable)
+
+	weight = -1
+	_, err = app.UpdateServer("test://a", ServerUpdate{Weight: &weight})
+	assert.NotNil(t, err)
+	_, err = app.UpdateServer("test://missing", ServerUpdate{Enable: &enable})
+	assert.True(t, errors.Is(err, ErrServerNotFound))
+
+	// the admin route updates the server as well
+	form := url.Values{"label": {"test://a"}, "enable": {"true"}, "healthy": {"false"}}
+	req := httptest.NewRequest(http.MethodPost, "/servers", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	app.handleServers(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	event = next()
+	assert.True(t, event.Service.Enable)
+	assert.False(t, event.Service.Healthy)
+
+	req = httptest.NewRequest(http.MethodPost, "/servers", strings.NewReader("label=test://a&weight=heavy"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	app.handleServers(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	for !source.send(syscall.SIGINT) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-errs:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("app not stopped")
+	}
+}
+
+func TestApplication_Metrics(t *testing.T) {
+	source := &testSignals{}
+	app, err := New(
+		WithFlagSet(flag.NewFlagSet("metrics", nil)),
+		WithSignalSource(source),
+		WithDisable(DisableDefaultGovernor),
+	)
+	assert.Nil(t, err)
+	handled := make(chan struct{}, 1)
+	app.HandleSignal(syscall.SIGUSR2, func(os.Signal) { handled <- struct{}{} })
+	assert.Nil(t, app.RegisterHooks(StageBeforeStop, func() error { return nil }))
+	assert.Nil(t, app.Startup())
+
+	errs := make(chan error, 1)
+	go func() { errs <- app.Run(newTestServer("a")) }()
+	for !source.send(syscall.SIGUSR2) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	<-handled
+	assert.True(t, source.send(syscall.SIGINT))
+	select {
+	case err := <-errs:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("app not stopped")
+	}
+
+	families, err := app.MetricsRegistry().Gather()
+	assert.Nil(t, err)
+	// labels of the samples of every family, name=value joined by comma
+	samples := make(map[string][]string)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make([]string, 0, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetName()+"="+label.GetValue())
+			}
+			samples[family.GetName()] = append(samples[family.GetName()], strings.Join(labels, ","))
+		}
+	}
+	assert.Contains(t, samples["jupiter_startup_stage_duration_seconds"], "result=ok,stage=loadConfig")
+	assert.Contains(t, samples["jupiter_server_serve_duration_seconds"], "result=ok,server=test://a")
+	assert.Contains(t, samples["jupiter_server_stop_duration_seconds"], "result=ok,server=test://a")
+	assert.Contains(t, samples["jupiter_signals_total"], "signal=user defined signal 2")
+	assert.Contains(t, samples["jupiter_signals_total"], "signal=interrupt")
+	assert.Len(t, samples["jupiter_hook_duration_seconds"], 1)
+	assert.Len(t, samples["jupiter_build_info"], 1)
+}
+
+func TestApplication_WatchRotatedSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jupiter")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer xlog.SetRedacted()
+	k1, _ := secret.NewKey("k1")
+	k2, _ := secret.NewKey("k2")
+	keys, config := filepath.Join(dir, "secret.keys"), filepath.Join(dir, "config.toml")
+	write := func(keyring secret.Keys, password string) {
+		value, err := secret.Encrypt(keyring, password)
+		assert.Nil(t, err)
+		lines := make([]string, 0, len(keyring))
+		for _, k := range keyring {
+			lines = append(lines, k.String())
+		}
+		assert.Nil(t, ioutil.WriteFile(keys, []byte(strings.Join(lines, "\n")), 0600))
+		assert.Nil(t, ioutil.WriteFile(config, []byte("[app.rotated]\npassword = \""+value+"\""), 0644))
+	}
+	write(secret.Keys{k1}, "first-secret")
+
+	app, err := New(
+		WithFlagSet(flag.NewFlagSet("rotated", []string{"--config=" + config, "--watch", "--secret-key-file=" + keys})),
+		WithSignalSource(&testSignals{}),
+		WithDisable(DisableDefaultGovernor),
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, app.Startup())
+	assert.Equal(t, "first-secret", conf.GetString("app.rotated.password"))
+
+	// a value encrypted by a rotated key is accepted by the watch reload
+	write(secret.Keys{k1, k2}, "second-secret")
+	assert.Eventually(t, func() bool {
+		return conf.GetString("app.rotated.password") == "second-secret"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotContains(t, conf.SecretValues(), "first-secret")
+}
+
+func TestApplication_ConfigSetEnv(t *testing.T) {
+	os.Setenv("JUPITER_CONFIG_SET", "app.set.name=demo, app.set.port=9090")
+	defer os.Unsetenv("JUPITER_CONFIG_SET")
+
+	app, err := New(
+		WithFlagSet(flag.NewFlagSet("set", nil)),
+		WithSignalSource(&testSignals{}),
+		WithDisable(DisableDefaultGovernor),
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, app.Startup())
+	assert.Equal(t, "demo", conf.GetString("app.set.name"))
+	assert.Equal(t, 9090, conf.GetInt("app.set.port"))
+}
+
+func TestApplication_SharedMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	for _, name := range []string{"a", "b"} {
+		app, err := New(
+			WithFlagSet(flag.NewFlagSet(name, nil)),
+			WithSignalSource(&testSignals{}),
+			WithMetricsRegistry(reg),
+			WithDisable(DisableDefaultGovernor),
+		)
+		assert.Nil(t, err)
+		assert.Nil(t, app.Startup())
+		for _, worker := range []string{"consumer", "consumer-" + name} {
+			config := xsupervisor.DefaultConfig()
+			config.Name = worker
+			assert.Nil(t, app.Schedule(config.Build(&contextWorker{stopped: make(chan struct{})})))
+		}
+	}
+
+	// the workers of both applications are reported, those of the same name once
+	families, err := reg.Gather()
+	assert.Nil(t, err)
+	var workers []string
+	for _, family := range families {
+		if family.GetName() == "jupiter_worker_restarts_total" {
+			for _, metric := range family.GetMetric() {
+				workers = append(workers, metric.GetLabel()[0].GetValue())
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"consumer", "consumer-a", "consumer-b"}, workers)
+}
+
+type testJob struct {
+	name  string
+	runID chan string
+	run   func(ctx context.Context) error
+}
+
+func (j *testJob) GetJobName() string { return j.name }
+
+func (j *testJob) RunE(ctx context.Context) error {
+	j.runID <- xjob.RunID(ctx)
+	return j.run(ctx)
+}
+
+func runJob(t *testing.T, source *testSignals, job *testJob) (*Application, <-chan error) {
+	app, err := New(
+		WithFlagSet(flag.NewFlagSet(job.name, []string{"--job=" + job.name})),
+		WithSignalSource(source),
+		WithDisable(DisableDefaultGovernor),
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, app.Startup())
+	assert.Nil(t, app.JobE(job))
+	assert.Equal(t, []string{job.name}, app.jobNames())
+
+	errs := make(chan error, 1)
+	go func() { errs <- app.Run() }()
+	select {
+	case id := <-job.runID:
+		assert.NotEmpty(t, id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("job not run")
+	}
+	return app, errs
+}
+
+func TestApplication_JobE(t *testing.T) {
+	_, errs := runJob(t, &testSignals{}, &testJob{name: "fail", runID: make(chan string, 1), run: func(ctx context.Context) error {
+		return xjob.Exit(3, errors.New("2 rows left"))
+	}})
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "job fail: exit 3: 2 rows left")
+		assert.Equal(t, 3, xjob.ExitCode(err))
+	case <-time.After(5 * time.Second):
+		t.Fatal("failed job did not fail run")
+	}
+}
+
+func TestApplication_JobOnly(t *testing.T) {
+	app, errs := runJob(t, &testSignals{}, &testJob{name: "sync", runID: make(chan string, 1), run: func(ctx context.Context) error {
+		return nil
+	}})
+	select {
+	case err := <-errs:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("run not returned after the job")
+	}
+	// app is stopped once the job returns
+	assert.NotNil(t, app.cycle.Context().Err())
+	assert.NotNil(t, app.shutdownReport)
+}
+
+func TestApplication_RunAndExit(t *testing.T) {
+	app := newJobApp(t, "--job=fail", "--job-args=since=1h")
+	assert.Nil(t, app.JobE(&describedJob{name: "fail", args: make(chan xjob.Args, 1)}))
+	codes := make(chan int, 1)
+	app.exit = func(code int) { codes <- code }
+
+	app.RunAndExit(newTestServer("a"))
+	assert.Equal(t, xjob.ExitFailure, <-codes)
+}
+
+func TestApplication_JobInterrupt(t *testing.T) {
+	source := &testSignals{}
+	app, errs := runJob(t, source, &testJob{name: "wait", runID: make(chan string, 1), run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	for !source.send(syscall.SIGINT) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-errs:
+		assert.Equal(t, xjob.ExitInterrupted, xjob.ExitCode(err))
+	case <-time.After(5 * time.Second):
+		t.Fatal("job not interrupted")
+	}
+	// servers are not served after an interrupted job
+	assert.Empty(t, app.cycle.Tasks())
+}
+
+// describedJob is a job of the arguments of --job-args
+type describedJob struct {
+	name string
+	args chan xjob.Args
+}
+
+func (j *describedJob) GetJobName() string { return j.name }
+
+func (j *describedJob) Description() string { return "sync " + j.name }
+
+func (j *describedJob) Args() []xjob.Arg {
+	return []xjob.Arg{
+		{Name: "since", Type: xjob.ArgDuration, Usage: "sync changes since", Required: true},
+		{Name: "limit", Type: xjob.ArgInt, Usage: "rows to sync", Default: "100"},
+	}
+}
+
+func (j *describedJob) RunE(ctx context.Context) error {
+	j.args <- xjob.ArgsFrom(ctx)
+	switch j.name {
+	case "fail":
+		return errors.New("no rows")
+	case "wait":
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func newJobApp(t *testing.T, args ...string) *Application {
+	app, err := New(
+		WithFlagSet(flag.NewFlagSet("jobs", args)),
+		WithSignalSource(&testSignals{}),
+		WithDisable(DisableDefaultGovernor),
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, app.Startup())
+	return app
+}
+
+func TestApplication_Jobs(t *testing.T) {
+	app := newJobApp(t, "--job=rooms, fail,users", "--job-args", "since=1h", "--job-args", "region=eu")
+	done := make(chan xjob.Args, 3)
+	for _, name := range []string{"rooms", "fail", "users"} {
+		assert.Nil(t, app.JobE(&describedJob{name: name, args: done}))
+	}
+
+	err := app.Run()
+	assert.EqualError(t, err, "job fail: no rows")
+	assert.Equal(t, xjob.ExitFailure, xjob.ExitCode(err))
+	// users is not run after fail in sequence
+	assert.Len(t, done, 2)
+	args := <-done
+	assert.Equal(t, time.Hour, args["since"])
+	assert.Equal(t, int64(100), args.Int("limit"))
+	assert.Equal(t, "eu", args.String("region"))
+
+	app = newJobApp(t, "--job=rooms,users", "--job-args=since=1h")
+	done = make(chan xjob.Args, 2)
+	for _, name := range []string{"rooms", "users"} {
+		assert.Nil(t, app.JobE(&describedJob{name: name, args: done}))
+	}
+	assert.Nil(t, app.Run())
+	assert.Len(t, done, 2)
+}
+
+func TestApplication_JobsParallel(t *testing.T) {
+	app := newJobApp(t, "--job=rooms,users", "--job-parallel", "--job-args=since=1h")
+	done := make(chan xjob.Args, 2)
+	for _, name := range []string{"rooms", "users"} {
+		assert.Nil(t, app.JobE(&describedJob{name: name, args: done}))
+	}
+	assert.Nil(t, app.Run())
+	assert.Len(t, done, 2)
+
+	// the failed job cancels the running ones, which are not failures of the run
+	app = newJobApp(t, "--job=rooms,fail,wait", "--job-parallel", "--job-args=since=1h")
+	done = make(chan xjob.Args, 3)
+	for _, name := range []string{"rooms", "fail", "wait"} {
+		assert.Nil(t, app.JobE(&describedJob{name: name, args: done}))
+	}
+	errs := make(chan error, 1)
+	go func() { errs <- app.Run() }()
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "job fail: no rows")
+		assert.Equal(t, xjob.ExitFailure, xjob.ExitCode(err))
+	case <-time.After(5 * time.Second):
+		t.Fatal("running jobs not cancelled by the failed job")
+	}
+	assert.Len(t, done, 3)
+}
+
+func TestApplication_JobsUsage(t *testing.T) {
+	app := newJobApp(t, "--job=rooms,users,orders")
+	assert.Nil(t, app.JobE(&describedJob{name: "rooms", args: make(chan xjob.Args, 1)}))
+	err := app.Run()
+	assert.EqualError(t, err, "exit 2: unknown job users, orders, see --list-jobs")
+	assert.Equal(t, xjob.ExitUsage, xjob.ExitCode(err))
+
+	app = newJobApp(t, "--job=rooms", "--job-args=since=yesterday")
+	assert.Nil(t, app.JobE(&describedJob{name: "rooms", args: make(chan xjob.Args, 1)}))
+	err = app.Run()
+	assert.Contains(t, err.Error(), "job rooms: invalid job arg since")
+	assert.Equal(t, xjob.ExitUsage, xjob.ExitCode(err))
+}
+
+func TestApplication_ListJobs(t *testing.T) {
+	app := newJobApp(t, "--list-jobs")
+	assert.Nil(t, app.JobE(&describedJob{name: "rooms", args: make(chan xjob.Args, 1)}))
+	assert.Nil(t, app.JobE(&testJob{name: "clean"}))
+	var b strings.Builder
+	app.listJobs(&b)
+	assert.Equal(t, "clean\t\nrooms\tsync rooms\n  since=<duration>\tsync changes since (required)\n  limit=<int>\trows to sync (default 100)\n", b.String())
+	assert.Nil(t, app.Run())
+}
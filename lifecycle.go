@@ -1,44 +1,284 @@
 package copy
 
 import (
+	"context"
+	"copy/pkg"
+	"copy/pkg/util/xtime"
+	"copy/pkg/xlog"
+	"errors"
+	"fmt"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/douyu/jupiter/pkg/ecode"
+	"go.uber.org/multierr"
+)
+
+// BuildInfo tells which build of the application is running.
+type BuildInfo struct {
+	Name           string `json:"name"`
+	AppID          string `json:"appID"`
+	AppVersion     string `json:"appVersion"`
+	JupiterVersion string `json:"jupiterVersion"`
+	GoVersion      string `json:"goVersion"`
+	BuildTime      string `json:"buildTime"`
+	BuildHost      string `json:"buildHost"`
+}
+
+// Build returns the build info of the running application.
+func Build() BuildInfo {
+	return BuildInfo{
+		Name:           pkg.Name(),
+		AppID:          pkg.AppID(),
+		AppVersion:     pkg.AppVersion(),
+		JupiterVersion: pkg.JupiterVersion(),
+		GoVersion:      pkg.GoVersion(),
+		BuildTime:      pkg.BuildTime(),
+		BuildHost:      pkg.BuildHost(),
+	}
+}
+
+// PanicError is the error a panic recovered from a task of Cycle turns into.
+type PanicError struct {
+	Task  string
+	Value interface{}
+	Stack []byte
+	Build BuildInfo
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task %s panic: %v", e.Task, e.Value)
+}
+
+// Unwrap returns the value of the panic if it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// TaskState is the state of a task of Cycle.
+type TaskState string
+
+const (
+	// TaskRunning is a task whose fn has not returned
+	TaskRunning TaskState = "running"
+	// TaskSucceeded is a task whose fn returned nil
+	TaskSucceeded TaskState = "succeeded"
+	// TaskFailed is a task whose fn returned an error or panicked
+	TaskFailed TaskState = "failed"
+	// TaskCancelled is a task whose fn returned context.Canceled or context.DeadlineExceeded
+	TaskCancelled TaskState = "cancelled"
 )
 
+// TaskStatus is a snapshot of a task of Cycle, End is zero while it is running.
+type TaskStatus struct {
+	Name  string    `json:"name"`
+	State TaskState `json:"state"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end,omitempty"`
+	Err   error     `json:"-"`
+	Error string    `json:"error,omitempty"`
+}
+
+// CyclePolicy tells what a Cycle does once a task fails.
+type CyclePolicy int
+
+const (
+	// FailFast cancels the context of every task on the first failed task
+	FailFast CyclePolicy = iota
+	// KeepGoing leaves the other tasks running, the context is cancelled by Cancel only
+	KeepGoing
+)
+
+// Cycle runs named tasks in goroutines. The first error is delivered on Wait, every
+// error is kept for Err, tasks never block on reporting. Tasks share a context which
+// is cancelled by Cancel, Close or a failed task under FailFast, like errgroup.
 type Cycle struct {
+	logger  *xlog.Logger
+	clock   xtime.Clock
+	ctx     *taskContext
+	cancel  context.CancelFunc
+	policy  CyclePolicy
 	mu      *sync.Mutex
 	wg      *sync.WaitGroup
 	done    chan struct{}
 	quit    chan error
 	closing uint32
 	waiting uint32
-	// works   []func() error
+
+	// smu guards the task states, mu is held while waiting for the tasks
+	smu   sync.Mutex
+	tasks []*TaskStatus
+	errs  error
 }
 
+// NewCycle returns a Cycle whose tasks keep going when one fails.
 func NewCycle() *Cycle {
+	return NewContextCycle(context.Background(), KeepGoing)
+}
+
+// NewContextCycle returns a Cycle whose task context is derived from ctx, policy tells
+// whether the first failed task cancels it.
+func NewContextCycle(ctx context.Context, policy CyclePolicy) *Cycle {
+	ctx, cancel := context.WithCancel(ctx)
 	return &Cycle{
+		logger:  xlog.JupiterLogger,
+		clock:   xtime.SystemClock,
+		ctx:     &taskContext{Context: ctx},
+		cancel:  cancel,
+		policy:  policy,
 		mu:      &sync.Mutex{},
 		wg:      &sync.WaitGroup{},
 		done:    make(chan struct{}),
-		quit:    make(chan error),
+		quit:    make(chan error, 1),
 		closing: 0,
 		waiting: 0,
 	}
 }
 
-func (c *Cycle) Run(fn func() error) {
+// Run runs fn as the task of name in a new goroutine, see Go.
+func (c *Cycle) Run(name string, fn func() error) {
+	c.Go(name, func(context.Context) error {
+		return fn()
+	})
+}
+
+// Go runs fn as the task of name in a new goroutine with the context of c, a panic
+// of fn is recovered into a *PanicError and reported as an error of fn.
+func (c *Cycle) Go(name string, fn func(ctx context.Context) error) {
 	c.mu.Lock()
-	//todo add check options panic before waiting
 	defer c.mu.Unlock()
+	task := &TaskStatus{Name: name, State: TaskRunning, Start: c.clock.Now()}
+	c.smu.Lock()
+	c.tasks = append(c.tasks, task)
+	c.smu.Unlock()
+
 	c.wg.Add(1)
 	go func(c *Cycle) {
 		defer c.wg.Done()
-		if err := fn(); err != nil {
-			c.quit <- err
-		}
+		c.finish(task, c.protect(name, func() error {
+			return fn(c.ctx)
+		}))
 	}(c)
 }
 
+// finish records the result of task, the first error is sent to Wait unless it is closed
+func (c *Cycle) finish(task *TaskStatus, err error) {
+	c.smu.Lock()
+	defer c.smu.Unlock()
+	task.End = c.clock.Now()
+	switch {
+	case err == nil:
+		task.State = TaskSucceeded
+		return
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		task.State = TaskCancelled
+	default:
+		task.State = TaskFailed
+		if c.policy == FailFast {
+			c.cancel()
+		}
+	}
+	task.Err = err
+	task.Error = err.Error()
+	c.errs = multierr.Append(c.errs, fmt.Errorf("task %s: %w", task.Name, err))
+	if atomic.LoadUint32(&c.closing) == 0 {
+		select {
+		case c.quit <- err:
+		default:
+		}
+	}
+}
+
+// Tasks returns the status of every task in run order.
+func (c *Cycle) Tasks() []TaskStatus {
+	c.smu.Lock()
+	defer c.smu.Unlock()
+	tasks := make([]TaskStatus, 0, len(c.tasks))
+	for _, task := range c.tasks {
+		tasks = append(tasks, *task)
+	}
+	return tasks
+}
+
+// Context returns the context of the tasks.
+func (c *Cycle) Context() context.Context {
+	return c.ctx
+}
+
+// Cancel cancels the context of the tasks, they are told to return.
+func (c *Cycle) Cancel() {
+	c.cancel()
+}
+
+// CancelBy cancels the context of the tasks like Cancel, its Deadline becomes the
+// one of ctx, so the tasks know how long they have left to return.
+func (c *Cycle) CancelBy(ctx context.Context) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.ctx.setDeadline(deadline)
+	}
+	c.cancel()
+}
+
+// taskContext is the context of the cycle tasks, whose deadline is set by CancelBy
+type taskContext struct {
+	context.Context
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+func (t *taskContext) Deadline() (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.deadline.IsZero() {
+		return t.deadline, true
+	}
+	return t.Context.Deadline()
+}
+
+func (t *taskContext) setDeadline(deadline time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if parent, ok := t.Context.Deadline(); ok && parent.Before(deadline) {
+		deadline = parent
+	}
+	t.deadline = deadline
+}
+
+// failures combines the errors of the failed tasks, cancelled tasks are left out
+func (c *Cycle) failures() error {
+	c.smu.Lock()
+	defer c.smu.Unlock()
+	var errs error
+	for _, task := range c.tasks {
+		if task.State == TaskFailed {
+			errs = multierr.Append(errs, fmt.Errorf("task %s: %w", task.Name, task.Err))
+		}
+	}
+	return errs
+}
+
+// Err combines the errors of every failed or cancelled task, it is complete once Done is closed.
+func (c *Cycle) Err() error {
+	c.smu.Lock()
+	defer c.smu.Unlock()
+	return c.errs
+}
+
+// protect calls fn of task, a panic is logged and returned as a *PanicError
+func (c *Cycle) protect(task string, fn func() error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			pe := &PanicError{Task: task, Value: rec, Stack: debug.Stack(), Build: Build()}
+			c.logger.Error("task panic", xlog.FieldMod(ecode.ModApp), xlog.FieldName(task), xlog.Any("panic", rec), xlog.FieldStack(pe.Stack), xlog.Any("build", pe.Build))
+			err = pe
+		}
+	}()
+	return fn()
+}
+
 func (c *Cycle) Done() <-chan struct{} {
 	if atomic.CompareAndSwapUint32(&c.waiting, 0, 1) {
 		go func(c *Cycle) {
@@ -56,15 +296,19 @@ func (c *Cycle) DoneAndClose() {
 	c.Close()
 }
 
-//Close ..
+// Close closes Wait and cancels the context, errors after Close are kept for Err only
 func (c *Cycle) Close() {
+	c.cancel()
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.smu.Lock()
+	defer c.smu.Unlock()
 	if atomic.CompareAndSwapUint32(&c.closing, 0, 1) {
 		close(c.quit)
 	}
 }
 
+// Wait delivers the first error of the tasks, it is closed by Close
 func (c *Cycle) Wait() <-chan error {
 	return c.quit
 }
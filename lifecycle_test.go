@@ -0,0 +1,255 @@
+package copy
+
+import (
+	"context"
+	"copy/pkg/flag"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+)
+
+func TestCycle_Panic(t *testing.T) {
+	c := NewCycle()
+	c.Run("panic", func() error {
+		panic(errors.New("nil map"))
+	})
+	select {
+	case err := <-c.Wait():
+		var pe *PanicError
+		if assert.True(t, errors.As(err, &pe)) {
+			assert.Equal(t, "panic", pe.Task)
+			assert.Contains(t, string(pe.Stack), "lifecycle_test.go")
+			assert.Equal(t, Build(), pe.Build)
+			assert.EqualError(t, errors.Unwrap(err), "nil map")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("panic not delivered")
+	}
+	c.DoneAndClose()
+}
+
+func TestCycle_Tasks(t *testing.T) {
+	c := NewCycle()
+	release := make(chan struct{})
+	c.Run("ok", func() error { return nil })
+	c.Run("blocked", func() error {
+		<-release
+		return nil
+	})
+	// nobody reads Wait, failing tasks must not block
+	c.Run("fail", func() error { return errors.New("bind: address in use") })
+	c.Run("fail again", func() error { return errors.New("broken pipe") })
+	c.Run("cancel", func() error { return fmt.Errorf("stop: %w", context.DeadlineExceeded) })
+
+	states := func() map[string]TaskState {
+		states := make(map[string]TaskState)
+		for _, task := range c.Tasks() {
+			states[task.Name] = task.State
+		}
+		return states
+	}
+	assert.Eventually(t, func() bool {
+		return states()["cancel"] == TaskCancelled && states()["fail again"] == TaskFailed
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, TaskRunning, states()["blocked"])
+
+	close(release)
+	<-c.Done()
+	c.Close()
+
+	tasks := c.Tasks()
+	if assert.Len(t, tasks, 5) {
+		assert.Equal(t, "ok", tasks[0].Name)
+		assert.Equal(t, TaskSucceeded, tasks[0].State)
+		assert.Equal(t, TaskSucceeded, tasks[1].State)
+		assert.Equal(t, TaskFailed, tasks[2].State)
+		assert.EqualError(t, tasks[2].Err, "bind: address in use")
+		assert.Equal(t, "bind: address in use", tasks[2].Error)
+		for _, task := range tasks {
+			assert.False(t, task.End.Before(task.Start))
+		}
+	}
+	err := c.Err()
+	assert.Len(t, multierr.Errors(err), 3)
+	assert.Contains(t, err.Error(), "task fail: bind: address in use")
+	assert.Contains(t, err.Error(), "task fail again: broken pipe")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// only the first error is kept for Wait
+	first, ok := <-c.Wait()
+	assert.True(t, ok)
+	assert.NotNil(t, first)
+	_, ok = <-c.Wait()
+	assert.False(t, ok)
+}
+
+type panicServer struct {
+	*testServer
+}
+
+func (s panicServer) Serve() error {
+	panic("bad listener")
+}
+
+func TestApplication_ServePanic(t *testing.T) {
+	app, err := New(
+		WithFlagSet(flag.NewFlagSet("panic", nil)),
+		WithSignalSource(&testSignals{}),
+		WithDisable(DisableDefaultGovernor),
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, app.Startup())
+
+	errs := make(chan error, 1)
+	go func() { errs <- app.Run(panicServer{newTestServer("p")}) }()
+	select {
+	case err := <-errs:
+		var pe *PanicError
+		if assert.True(t, errors.As(err, &pe)) {
+			assert.Equal(t, "test://p", pe.Task)
+			assert.Equal(t, "bad listener", pe.Value)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("panic not delivered")
+	}
+}
+
+func TestCycle_Policy(t *testing.T) {
+	run := func(policy CyclePolicy) *Cycle {
+		c := NewContextCycle(context.Background(), policy)
+		c.Go("sibling", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		c.Run("fail", func() error { return errors.New("bind: address in use") })
+		return c
+	}
+
+	c := run(FailFast)
+	<-c.Done()
+	c.Close()
+	tasks := c.Tasks()
+	assert.Equal(t, TaskCancelled, tasks[0].State)
+	assert.Equal(t, TaskFailed, tasks[1].State)
+	assert.EqualError(t, c.failures(), "task fail: bind: address in use")
+
+	c = run(KeepGoing)
+	assert.Eventually(t, func() bool {
+		return c.Tasks()[1].State == TaskFailed
+	}, time.Second, time.Millisecond)
+	assert.Nil(t, c.Context().Err())
+	assert.Equal(t, TaskRunning, c.Tasks()[0].State)
+	c.Cancel()
+	c.DoneAndClose()
+	assert.Equal(t, TaskCancelled, c.Tasks()[0].State)
+}
+
+func TestCycle_CancelBy(t *testing.T) {
+	c := NewContextCycle(context.Background(), FailFast)
+	deadlines := make(chan time.Time, 1)
+	c.Go("drain", func(ctx context.Context) error {
+		<-ctx.Done()
+		deadline, _ := ctx.Deadline()
+		deadlines <- deadline
+		return ctx.Err()
+	})
+	_, ok := c.Context().Deadline()
+	assert.False(t, ok)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	c.CancelBy(ctx)
+	want, _ := ctx.Deadline()
+	assert.Equal(t, want, <-deadlines)
+	c.DoneAndClose()
+	assert.Equal(t, TaskCancelled, c.Tasks()[0].State)
+}
+
+type failServer struct {
+	*testServer
+}
+
+func (s failServer) Serve() error {
+	return errors.New("bind: address in use")
+}
+
+type contextWorker struct {
+	stopped chan struct{}
+}
+
+func (w *contextWorker) Run() error { panic("RunContext is run") }
+
+func (w *contextWorker) RunContext(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (w *contextWorker) Stop() error {
+	close(w.stopped)
+	return nil
+}
+
+func TestApplication_FailFast(t *testing.T) {
+	app, err := New(
+		WithFlagSet(flag.NewFlagSet("failfast", nil)),
+		WithSignalSource(&testSignals{}),
+		WithDisable(DisableDefaultGovernor),
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, app.Startup())
+	w := &contextWorker{stopped: make(chan struct{})}
+	assert.Nil(t, app.Schedule(w))
+
+	sibling := newTestServer("sibling")
+	errs := make(chan error, 1)
+	go func() { errs <- app.Run(sibling, failServer{newTestServer("fail")}) }()
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "task serve test://fail: bind: address in use")
+	case <-time.After(5 * time.Second):
+		t.Fatal("failed server did not stop app")
+	}
+	// the sibling server and the worker were stopped
+	<-sibling.stopped
+	<-w.stopped
+	states := make(map[string]TaskState)
+	for _, task := range app.cycle.Tasks() {
+		states[task.Name] = task.State
+	}
+	assert.Equal(t, TaskSucceeded, states["serve test://sibling"])
+	assert.Equal(t, TaskCancelled, states["worker "+workerName(w)])
+}
+
+func TestApplication_KeepGoing(t *testing.T) {
+	source := &testSignals{}
+	app, err := New(
+		WithFlagSet(flag.NewFlagSet("keepgoing", nil)),
+		WithSignalSource(source),
+		WithDisable(DisableDefaultGovernor),
+		WithCyclePolicy(KeepGoing),
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, app.Startup())
+
+	errs := make(chan error, 1)
+	go func() { errs <- app.Run(newTestServer("sibling"), failServer{newTestServer("fail")}) }()
+	select {
+	case <-errs:
+		t.Fatal("app stopped by a failed server")
+	case <-time.After(100 * time.Millisecond):
+	}
+	for !source.send(syscall.SIGINT) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "task serve test://fail: bind: address in use")
+	case <-time.After(5 * time.Second):
+		t.Fatal("app not stopped")
+	}
+}
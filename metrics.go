@@ -0,0 +1,186 @@
+package copy
+
+import (
+	"copy/pkg/util/xhook"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "jupiter"
+
+// Metric results
+const (
+	resultOK     = "ok"
+	resultError  = "error"
+	resultForced = "forced"
+	resultPanic  = "panic"
+	resultCancel = "cancelled"
+)
+
+// appMetrics are the lifecycle metrics of an Application, kept in its metrics
+// registry, see WithMetricsRegistry.
+type appMetrics struct {
+	stageDuration *prometheus.HistogramVec
+	serveDuration *prometheus.HistogramVec
+	stopDuration  *prometheus.HistogramVec
+	jobDuration   *prometheus.HistogramVec
+	hookDuration  *prometheus.HistogramVec
+	signals       *prometheus.CounterVec
+	buildInfo     *prometheus.GaugeVec
+}
+
+// lifecycleBuckets span from a fast hook to a server serving for days
+var lifecycleBuckets = []float64{.001, .01, .1, .5, 1, 5, 10, 30, 60, 300, 3600, 86400}
+
+func newAppMetrics(app *Application) *appMetrics {
+	m := &appMetrics{
+		stageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "startup_stage_duration_seconds",
+			Help:      "Duration of startup stages.",
+			Buckets:   lifecycleBuckets,
+		}, []string{"stage", "result"}),
+		serveDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "server_serve_duration_seconds",
+			Help:      "How long servers served until Serve returned.",
+			Buckets:   lifecycleBuckets,
+		}, []string{"server", "result"}),
+		stopDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "server_stop_duration_seconds",
+			Help:      "Duration of server stops, result is forced for servers stopped through Stop.",
+			Buckets:   lifecycleBuckets,
+		}, []string{"server", "result"}),
+		jobDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "job_duration_seconds",
+			Help:      "Duration of job runs by result, the count is the number of runs.",
+			Buckets:   lifecycleBuckets,
+		}, []string{"job", "result"}),
+		hookDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "hook_duration_seconds",
+			Help:      "Duration of stage hooks.",
+			Buckets:   lifecycleBuckets,
+		}, []string{"stage", "hook", "result"}),
+		signals: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "signals_total",
+			Help:      "Signals received.",
+		}, []string{"signal"}),
+		buildInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "build_info",
+			Help:      "Build info of the application, the value is 1.",
+		}, []string{"name", "app_id", "app_version", "jupiter_version", "go_version", "build_time", "build_host"}),
+	}
+	m.stageDuration = registerCollector(app.metrics, m.stageDuration).(*prometheus.HistogramVec)
+	m.serveDuration = registerCollector(app.metrics, m.serveDuration).(*prometheus.HistogramVec)
+	m.stopDuration = registerCollector(app.metrics, m.stopDuration).(*prometheus.HistogramVec)
+	m.jobDuration = registerCollector(app.metrics, m.jobDuration).(*prometheus.HistogramVec)
+	m.hookDuration = registerCollector(app.metrics, m.hookDuration).(*prometheus.HistogramVec)
+	m.signals = registerCollector(app.metrics, m.signals).(*prometheus.CounterVec)
+	m.buildInfo = registerCollector(app.metrics, m.buildInfo).(*prometheus.GaugeVec)
+	workers := registerCollector(app.metrics, &workerCollector{}).(*workerCollector)
+	workers.add(app)
+
+	build := Build()
+	m.buildInfo.WithLabelValues(build.Name, build.AppID, build.AppVersion, build.JupiterVersion, build.GoVersion, build.BuildTime, build.BuildHost).Set(1)
+	return m
+}
+
+// registerCollector registers c in reg, the collector registered before is returned
+// if reg is shared by several applications
+func registerCollector(reg prometheus.Registerer, c prometheus.Collector) prometheus.Collector {
+	if err := reg.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+		panic(err)
+	}
+	return c
+}
+
+func (m *appMetrics) observeStage(stage string, cost time.Duration, err error) {
+	m.stageDuration.WithLabelValues(stage, result(err)).Observe(cost.Seconds())
+}
+
+func (m *appMetrics) observeServe(server string, cost time.Duration, err error) {
+	m.serveDuration.WithLabelValues(server, result(err)).Observe(cost.Seconds())
+}
+
+func (m *appMetrics) observeStop(r StopResult) {
+	res := result(r.Err)
+	if r.Forced {
+		res = resultForced
+	}
+	m.stopDuration.WithLabelValues(r.Name, res).Observe(r.Cost.Seconds())
+}
+
+func (m *appMetrics) observeJob(job string, cost time.Duration, res string) {
+	m.jobDuration.WithLabelValues(job, res).Observe(cost.Seconds())
+}
+
+func (m *appMetrics) observeHooks(stage string, report *xhook.Report) {
+	for _, r := range report.Results {
+		m.hookDuration.WithLabelValues(stage, r.Name, result(r.Err)).Observe(r.Cost.Seconds())
+	}
+}
+
+func (m *appMetrics) observeSignal(sig os.Signal) {
+	m.signals.WithLabelValues(sig.String()).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return resultError
+	}
+	return resultOK
+}
+
+// workerCollector reports the restarts of the scheduled workers at scrape time, one
+// collector covers the applications sharing a registry and sums their workers by name
+type workerCollector struct {
+	mu   sync.Mutex
+	apps []*Application
+}
+
+var workerRestartsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(metricsNamespace, "", "worker_restarts_total"),
+	"Restarts of scheduled workers by their supervisor.",
+	[]string{"worker"}, nil,
+)
+
+func (c *workerCollector) add(app *Application) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apps = append(c.apps, app)
+}
+
+func (c *workerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- workerRestartsDesc
+}
+
+func (c *workerCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	apps := c.apps
+	c.mu.Unlock()
+
+	var names []string
+	restarts := make(map[string]int)
+	for _, app := range apps {
+		for _, s := range app.WorkerStatus() {
+			if _, ok := restarts[s.Name]; !ok {
+				names = append(names, s.Name)
+			}
+			restarts[s.Name] += s.Restarts
+		}
+	}
+	for _, name := range names {
+		ch <- prometheus.MustNewConstMetric(workerRestartsDesc, prometheus.CounterValue, float64(restarts[name]), name)
+	}
+}
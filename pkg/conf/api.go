@@ -0,0 +1,204 @@
+package conf
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Unmarshaller ...
+type Unmarshaller = func([]byte, interface{}) error
+
+var defaultConfiguration = New()
+
+// UnmarshallerFor returns the Unmarshaller of a config file picked by its extension,
+// one of .toml, .yaml, .yml and .json.
+func UnmarshallerFor(path string) (Unmarshaller, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return toml.Unmarshal, true
+	case ".yaml", ".yml":
+		return yaml.Unmarshal, true
+	case ".json":
+		return json.Unmarshal, true
+	default:
+		return nil, false
+	}
+}
+
+// DataSource provides the content of a config layer and notifies its changes.
+type DataSource interface {
+	ReadConfig() ([]byte, error)
+	IsConfigChanged() <-chan struct{}
+	io.Closer
+}
+
+// OnChange registers fn called after keyPrefix of default configuration is modified.
+func OnChange(keyPrefix string, fn func(old, new interface{})) {
+	defaultConfiguration.OnChange(keyPrefix, fn)
+}
+
+// Validate registers fn checking every layer change of default configuration.
+func Validate(fn func(*Configuration) error) {
+	defaultConfiguration.Validate(fn)
+}
+
+// Load merges content into the layer LayerLoad of default configuration.
+func Load(content []byte, unmarshaller Unmarshaller) error {
+	return defaultConfiguration.Load(content, unmarshaller)
+}
+
+// LoadFromReader loads configuration from reader into default configuration.
+func LoadFromReader(r io.Reader, unmarshaller Unmarshaller) error {
+	return defaultConfiguration.LoadFromReader(r, unmarshaller)
+}
+
+// SetLayer replaces the values of a layer of default configuration.
+func SetLayer(name string, priority int, values map[string]interface{}) error {
+	return defaultConfiguration.SetLayer(name, priority, values)
+}
+
+// LoadLayer unmarshals content as the values of a layer of default configuration.
+func LoadLayer(name string, priority int, content []byte, unmarshaller Unmarshaller) error {
+	return defaultConfiguration.LoadLayer(name, priority, content, unmarshaller)
+}
+
+// LoadEnv sets the env layer of default configuration.
+func LoadEnv(lookup func(string) (string, bool)) error {
+	return defaultConfiguration.LoadEnv(lookup)
+}
+
+// LoadFlags sets the flag layer of default configuration.
+func LoadFlags(overrides []string) error {
+	return defaultConfiguration.LoadFlags(overrides)
+}
+
+// Layers returns the layer names of default configuration.
+func Layers() []string {
+	return defaultConfiguration.Layers()
+}
+
+// Provenance returns the layer name of key in default configuration.
+func Provenance(key string) string {
+	return defaultConfiguration.Provenance(key)
+}
+
+// Provenances returns the layer names of all keys in default configuration.
+func Provenances() map[string]string {
+	return defaultConfiguration.Provenances()
+}
+
+// Keys returns the sorted leaf keys of default configuration.
+func Keys() []string {
+	return defaultConfiguration.Keys()
+}
+
+// Reset ...
+func Reset() {
+	defaultConfiguration = New()
+}
+
+// Traverse ...
+func Traverse(sep string) map[string]interface{} {
+	return defaultConfiguration.traverse(sep)
+}
+
+// Get ...
+func Get(key string) interface{} {
+	return defaultConfiguration.Get(key)
+}
+
+// Set ...
+func Set(key string, val interface{}) error {
+	return defaultConfiguration.Set(key, val)
+}
+
+// SetDefault ...
+func SetDefault(key string, val interface{}) error {
+	return defaultConfiguration.SetDefault(key, val)
+}
+
+// GetString returns the value associated with the key as a string with default defaultConfiguration.
+func GetString(key string) string {
+	return defaultConfiguration.GetString(key)
+}
+
+// GetBool returns the value associated with the key as a boolean with default defaultConfiguration.
+func GetBool(key string) bool {
+	return defaultConfiguration.GetBool(key)
+}
+
+// GetInt returns the value associated with the key as an integer with default defaultConfiguration.
+func GetInt(key string) int {
+	return defaultConfiguration.GetInt(key)
+}
+
+// GetInt64 returns the value associated with the key as an integer with default defaultConfiguration.
+func GetInt64(key string) int64 {
+	return defaultConfiguration.GetInt64(key)
+}
+
+// GetFloat64 returns the value associated with the key as a float64 with default defaultConfiguration.
+func GetFloat64(key string) float64 {
+	return defaultConfiguration.GetFloat64(key)
+}
+
+// GetTime returns the value associated with the key as time with default defaultConfiguration.
+func GetTime(key string) time.Time {
+	return defaultConfiguration.GetTime(key)
+}
+
+// GetDuration returns the value associated with the key as a duration with default defaultConfiguration.
+func GetDuration(key string) time.Duration {
+	return defaultConfiguration.GetDuration(key)
+}
+
+// GetStringSlice returns the value associated with the key as a slice of strings with default defaultConfiguration.
+func GetStringSlice(key string) []string {
+	return defaultConfiguration.GetStringSlice(key)
+}
+
+// GetSlice returns the value associated with the key as a slice with default defaultConfiguration.
+func GetSlice(key string) []interface{} {
+	return defaultConfiguration.GetSlice(key)
+}
+
+// GetStringMap returns the value associated with the key as a map of interfaces with default defaultConfiguration.
+func GetStringMap(key string) map[string]interface{} {
+	return defaultConfiguration.GetStringMap(key)
+}
+
+// GetStringMapString returns the value associated with the key as a map of strings with default defaultConfiguration.
+func GetStringMapString(key string) map[string]string {
+	return defaultConfiguration.GetStringMapString(key)
+}
+
+// UnmarshalKey takes a single key and unmarshal it into a Struct with default defaultConfiguration.
+func UnmarshalKey(key string, rawVal interface{}, opts ...GetOption) error {
+	return defaultConfiguration.UnmarshalKey(key, rawVal, opts...)
+}
+
+// Invalid returns the invalid fields found by UnmarshalKey of default configuration.
+func Invalid() error {
+	return defaultConfiguration.Invalid()
+}
+
+// SetDecrypter sets the decrypter of ENC(...) values of default configuration.
+func SetDecrypter(decrypt Decrypter) {
+	defaultConfiguration.SetDecrypter(decrypt)
+}
+
+// SecretValues returns the live plaintexts of the decrypted values of default configuration.
+func SecretValues() []string {
+	return defaultConfiguration.SecretValues()
+}
+
+// IsSecret reports whether key of default configuration was decrypted.
+func IsSecret(key string) bool {
+	return defaultConfiguration.IsSecret(key)
+}
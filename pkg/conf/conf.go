@@ -0,0 +1,359 @@
+package conf
+
+import (
+	"copy/pkg/secret"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/douyu/jupiter/pkg/util/xcast"
+	"github.com/mitchellh/mapstructure"
+)
+
+// Configuration provides configuration for application.
+// Values are merged from layers, a layer of higher priority overrides the lower ones.
+type Configuration struct {
+	mu       sync.RWMutex
+	keyDelim string
+	layers   []*layer
+	// override is the merged values of all layers
+	override map[string]interface{}
+	// provenance maps every effective key to the name of its layer
+	provenance map[string]string
+
+	watchers   []watcher
+	validators []func(*Configuration) error
+	// schemas are the structs unmarshalled by key, invalid are their invalid fields by key
+	schemas map[string]schema
+	invalid map[string][]*FieldError
+	// secrets are the keys of decrypted values, masked in dumps
+	decrypt Decrypter
+	secrets map[string]struct{}
+	// envLookup is the lookup of LoadEnv, nil until the env layer is loaded
+	envLookup func(string) (string, bool)
+}
+
+type watcher struct {
+	prefix string
+	fn     func(old, new interface{})
+}
+
+const (
+	defaultKeyDelim = "."
+)
+
+// ErrInvalidKey ...
+var ErrInvalidKey = errors.New("invalid key, maybe not exist in config")
+
+// New constructs a new Configuration.
+func New() *Configuration {
+	return &Configuration{
+		keyDelim:   defaultKeyDelim,
+		layers:     make([]*layer, 0),
+		override:   make(map[string]interface{}),
+		provenance: make(map[string]string),
+		schemas:    make(map[string]schema),
+		invalid:    make(map[string][]*FieldError),
+		secrets:    make(map[string]struct{}),
+	}
+}
+
+// SetKeyDelim set keyDelim of a Configuration instance.
+func (c *Configuration) SetKeyDelim(delim string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keyDelim = delim
+	_ = c.merge()
+}
+
+// Sub returns new Configuration instance representing a sub tree of this instance.
+func (c *Configuration) Sub(key string) *Configuration {
+	sub := New()
+	sub.keyDelim = c.keyDelim
+	_ = sub.SetLayer(key, PriorityDefault, c.GetStringMap(key))
+	return sub
+}
+
+// OnChange registers fn called with the old and new value of keyPrefix after a layer
+// change modifies it. The value is a map for a non-leaf key and nil for a missing key.
+func (c *Configuration) OnChange(keyPrefix string, fn func(old, new interface{})) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.watchers = append(c.watchers, watcher{prefix: keyPrefix, fn: fn})
+}
+
+// Validate registers fn checking the configuration of every layer change before it is
+// applied, a change failed by fn is rejected and the live configuration keeps untouched.
+func (c *Configuration) Validate(fn func(*Configuration) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.validators = append(c.validators, fn)
+}
+
+// Load merges content into the layer LayerLoad.
+func (c *Configuration) Load(content []byte, unmarshal Unmarshaller) error {
+	values := make(map[string]interface{})
+	if err := unmarshal(content, &values); err != nil {
+		return err
+	}
+	merged := c.layerValues(LayerLoad)
+	mergeMap(merged, normalizeMap(values))
+	return c.SetLayer(LayerLoad, PriorityFile, merged)
+}
+
+// LoadFromReader loads configuration from reader into the layer LayerLoad.
+func (c *Configuration) LoadFromReader(reader io.Reader, unmarshaller Unmarshaller) error {
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return c.Load(content, unmarshaller)
+}
+
+// Set sets the value of key in the layer LayerSet, which overrides all other layers.
+func (c *Configuration) Set(key string, val interface{}) error {
+	values := c.layerValues(LayerSet)
+	c.setValue(values, key, val)
+	return c.SetLayer(LayerSet, PrioritySet, values)
+}
+
+// SetDefault sets the value of key in the layer LayerDefault, which is overridden by all other layers.
+func (c *Configuration) SetDefault(key string, val interface{}) error {
+	values := c.layerValues(LayerDefault)
+	c.setValue(values, key, val)
+	return c.SetLayer(LayerDefault, PriorityDefault, values)
+}
+
+func (c *Configuration) setValue(m map[string]interface{}, key string, val interface{}) {
+	c.mu.RLock()
+	paths := strings.Split(key, c.keyDelim)
+	c.mu.RUnlock()
+	lastKey := paths[len(paths)-1]
+	m = deepSearch(m, paths[:len(paths)-1])
+	m[lastKey] = normalize(val)
+}
+
+func deepSearch(m map[string]interface{}, path []string) map[string]interface{} {
+	for _, k := range path {
+		m2, ok := m[k]
+		if !ok {
+			m3 := make(map[string]interface{})
+			m[k] = m3
+			m = m3
+			continue
+		}
+		m3, ok := m2.(map[string]interface{})
+		if !ok {
+			m3 = make(map[string]interface{})
+			m[k] = m3
+		}
+		m = m3
+	}
+	return m
+}
+
+// Get returns the value associated with the key
+func (c *Configuration) Get(key string) interface{} {
+	return c.find(key)
+}
+
+// GetString returns the value associated with the key as a string.
+func (c *Configuration) GetString(key string) string {
+	return xcast.ToString(c.Get(key))
+}
+
+// GetBool returns the value associated with the key as a boolean.
+func (c *Configuration) GetBool(key string) bool {
+	return xcast.ToBool(c.Get(key))
+}
+
+// GetInt returns the value associated with the key as an integer.
+func (c *Configuration) GetInt(key string) int {
+	return xcast.ToInt(c.Get(key))
+}
+
+// GetInt64 returns the value associated with the key as an integer.
+func (c *Configuration) GetInt64(key string) int64 {
+	return xcast.ToInt64(c.Get(key))
+}
+
+// GetFloat64 returns the value associated with the key as a float64.
+func (c *Configuration) GetFloat64(key string) float64 {
+	return xcast.ToFloat64(c.Get(key))
+}
+
+// GetTime returns the value associated with the key as time.
+func (c *Configuration) GetTime(key string) time.Time {
+	return xcast.ToTime(c.Get(key))
+}
+
+// GetDuration returns the value associated with the key as a duration.
+func (c *Configuration) GetDuration(key string) time.Duration {
+	return xcast.ToDuration(c.Get(key))
+}
+
+// GetStringSlice returns the value associated with the key as a slice of strings.
+func (c *Configuration) GetStringSlice(key string) []string {
+	return xcast.ToStringSlice(c.Get(key))
+}
+
+// GetSlice returns the value associated with the key as a slice.
+func (c *Configuration) GetSlice(key string) []interface{} {
+	return xcast.ToSlice(c.Get(key))
+}
+
+// GetStringMap returns the value associated with the key as a map of interfaces.
+func (c *Configuration) GetStringMap(key string) map[string]interface{} {
+	return xcast.ToStringMap(c.Get(key))
+}
+
+// GetStringMapString returns the value associated with the key as a map of strings.
+func (c *Configuration) GetStringMapString(key string) map[string]string {
+	return xcast.ToStringMapString(c.Get(key))
+}
+
+// UnmarshalKey takes a single key and unmarshal it into a Struct.
+// String values of env and flag layers are converted to the field types.
+// The struct is checked by its validate tags, see ValidateStruct. Invalid fields are
+// kept for Invalid and returned in a *ValidationError after the struct is decoded,
+// and later layer changes invalidating the key are rejected.
+func (c *Configuration) UnmarshalKey(key string, rawVal interface{}, opts ...GetOption) error {
+	var options = defaultGetOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	// the defaults of rawVal validate later layer changes,
+	// and their fields are looked up in the environment
+	defaults := copyStruct(rawVal)
+	var envErr error
+	if defaults.IsValid() {
+		envErr = c.addSchema(key, schema{defaults: defaults, options: options})
+	}
+
+	var value interface{}
+	if key == "" {
+		c.mu.RLock()
+		value = c.override
+		c.mu.RUnlock()
+	} else if value = c.Get(key); value == nil {
+		return fmt.Errorf("%s: %w", key, ErrInvalidKey)
+	}
+
+	if err := decode(value, rawVal, options); err != nil {
+		return err
+	}
+	if !defaults.IsValid() {
+		return nil
+	}
+
+	c.mu.RLock()
+	err := c.maskFields(validateStruct(key, rawVal, options.TagName))
+	c.mu.RUnlock()
+	c.record(key, err)
+	if err == nil {
+		err = envErr
+	}
+	return err
+}
+
+func decode(input interface{}, rawVal interface{}, options GetOptions) error {
+	config := mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           rawVal,
+		TagName:          options.TagName,
+	}
+	decoder, err := mapstructure.NewDecoder(&config)
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(input)
+}
+
+// Provenance returns the name of the layer the effective value of key comes from,
+// empty if key is not a leaf key of the configuration.
+func (c *Configuration) Provenance(key string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.provenance[key]
+}
+
+// Provenances returns the layer names of all effective keys.
+func (c *Configuration) Provenances() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	provenance := make(map[string]string, len(c.provenance))
+	for k, v := range c.provenance {
+		provenance[k] = v
+	}
+	return provenance
+}
+
+// Keys returns the sorted effective leaf keys.
+func (c *Configuration) Keys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make([]string, 0, len(c.provenance))
+	for k := range c.provenance {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *Configuration) find(key string) interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return search(c.override, strings.Split(key, c.keyDelim))
+}
+
+func search(m map[string]interface{}, paths []string) interface{} {
+	var v interface{} = m
+	for _, k := range paths {
+		if k == "" {
+			continue
+		}
+		vm, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if v, ok = vm[k]; !ok {
+			return nil
+		}
+	}
+	return v
+}
+
+// traverse flattens the configuration with keys joined by sep, secrets are masked
+func (c *Configuration) traverse(sep string) map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	data := make(map[string]interface{})
+	flatten("", c.override, data, sep)
+	for key := range data {
+		if c.isSecret(strings.Replace(key, sep, c.keyDelim, -1)) {
+			data[key] = secret.Mask
+		}
+	}
+	return data
+}
+
+func flatten(prefix string, target map[string]interface{}, data map[string]interface{}, sep string) {
+	for k, v := range target {
+		pp := prefix + sep + k
+		if prefix == "" {
+			pp = k
+		}
+		if dd, ok := v.(map[string]interface{}); ok && len(dd) > 0 {
+			flatten(pp, dd, data, sep)
+		} else {
+			data[pp] = v
+		}
+	}
+}
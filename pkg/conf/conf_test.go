@@ -0,0 +1,191 @@
+package conf
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+const fileContent = `
+[jupiter.logger.default]
+	level = "info"
+	maxSize = 500
+	dir = "/var/log"
+[jupiter.server.http]
+	port = 8080
+`
+
+func TestConfiguration_Layers(t *testing.T) {
+	c := New()
+	assert.Nil(t, c.SetDefault("jupiter.logger.default.level", "error"))
+	assert.Nil(t, c.SetDefault("jupiter.logger.default.async", true))
+	assert.Nil(t, c.LoadLayer("config.toml", PriorityFile, []byte(fileContent), toml.Unmarshal))
+	assert.Nil(t, c.LoadEnv(func(key string) (string, bool) {
+		if key == "JUPITER_LOGGER_DEFAULT_LEVEL" {
+			return "warn", true
+		}
+		if key == "JUPITER_LOGGER_DEFAULT_DIR" {
+			return "/tmp", true
+		}
+		return "", false
+	}))
+	assert.Nil(t, c.LoadFlags([]string{"jupiter.logger.default.level=debug", "jupiter.app.name=demo"}))
+
+	assert.Equal(t, []string{LayerDefault, "config.toml", LayerEnv, LayerFlag}, c.Layers())
+	assert.Equal(t, "debug", c.GetString("jupiter.logger.default.level"))
+	assert.Equal(t, "/tmp", c.GetString("jupiter.logger.default.dir"))
+	assert.Equal(t, 500, c.GetInt("jupiter.logger.default.maxSize"))
+	assert.Equal(t, true, c.GetBool("jupiter.logger.default.async"))
+
+	assert.Equal(t, LayerFlag, c.Provenance("jupiter.logger.default.level"))
+	assert.Equal(t, LayerEnv, c.Provenance("jupiter.logger.default.dir"))
+	assert.Equal(t, "config.toml", c.Provenance("jupiter.server.http.port"))
+	assert.Equal(t, LayerDefault, c.Provenance("jupiter.logger.default.async"))
+	assert.Equal(t, LayerFlag, c.Provenance("jupiter.app.name"))
+	assert.Equal(t, "", c.Provenance("jupiter.logger.default"))
+}
+
+func TestConfiguration_LoadEnv(t *testing.T) {
+	c := New()
+	env := map[string]string{
+		"JUPITER_LOGGER_DEFAULT_LEVEL":   "debug",
+		"JUPITER_LOGGER_DEFAULT_MAXSIZE": "100",
+		"JUPITER_SERVER_HTTP_PORT":       "9090",
+	}
+	lookup := func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	}
+	assert.Nil(t, c.LoadEnv(lookup))
+
+	// keys only in the environment are found by the unmarshalled fields
+	config := struct {
+		Level   string
+		MaxSize int
+		Dir     string
+	}{Level: "info", Dir: "/var/log"}
+	assert.Nil(t, c.UnmarshalKey("jupiter.logger.default", &config))
+	assert.Equal(t, "debug", config.Level)
+	assert.Equal(t, 100, config.MaxSize)
+	assert.Equal(t, "/var/log", config.Dir)
+	assert.Equal(t, LayerEnv, c.Provenance("jupiter.logger.default.level"))
+	assert.Equal(t, 100, c.GetInt("jupiter.logger.default.maxSize"))
+
+	// and by the keys of a layer loaded later
+	assert.Nil(t, c.Get("jupiter.server.http.port"))
+	assert.Nil(t, c.LoadLayer("config.toml", PriorityFile, []byte(fileContent), toml.Unmarshal))
+	assert.Nil(t, c.LoadEnv(lookup))
+	assert.Equal(t, 9090, c.GetInt("jupiter.server.http.port"))
+}
+
+func TestConfiguration_SetLayer(t *testing.T) {
+	c := New()
+	assert.Nil(t, c.LoadLayer("config.toml", PriorityFile, []byte(fileContent), toml.Unmarshal))
+	assert.Nil(t, c.SetLayer(LayerDefault, PriorityDefault, map[string]interface{}{"jupiter": map[string]interface{}{"mode": "dev"}}))
+	assert.Equal(t, []string{LayerDefault, "config.toml"}, c.Layers())
+
+	// a reloaded layer keeps its place
+	assert.Nil(t, c.LoadLayer("config.toml", PriorityFile, []byte(`[jupiter.server.http]
+	port = 9090`), toml.Unmarshal))
+	assert.Equal(t, []string{LayerDefault, "config.toml"}, c.Layers())
+	assert.Equal(t, 9090, c.GetInt("jupiter.server.http.port"))
+	assert.Nil(t, c.Get("jupiter.logger.default.level"))
+	assert.Equal(t, "dev", c.GetString("jupiter.mode"))
+}
+
+func TestConfiguration_OnChange(t *testing.T) {
+	c := New()
+	type change struct{ old, new interface{} }
+	var levels, servers []change
+	c.OnChange("jupiter.logger.default.level", func(old, new interface{}) {
+		levels = append(levels, change{old, new})
+	})
+	c.OnChange("jupiter.server", func(old, new interface{}) {
+		servers = append(servers, change{old, new})
+	})
+
+	assert.Nil(t, c.LoadLayer("config.toml", PriorityFile, []byte(fileContent), toml.Unmarshal))
+	assert.Equal(t, []change{{nil, "info"}}, levels)
+	assert.Len(t, servers, 1)
+
+	// untouched keys are not notified
+	assert.Nil(t, c.LoadFlags([]string{"jupiter.logger.default.level=debug"}))
+	assert.Equal(t, []change{{nil, "info"}, {"info", "debug"}}, levels)
+	assert.Len(t, servers, 1)
+
+	assert.Nil(t, c.LoadLayer("config.toml", PriorityFile, []byte(`[jupiter.server.http]
+	port = 9090`), toml.Unmarshal))
+	assert.Len(t, levels, 2)
+	assert.Equal(t, change{
+		map[string]interface{}{"http": map[string]interface{}{"port": int64(8080)}},
+		map[string]interface{}{"http": map[string]interface{}{"port": int64(9090)}},
+	}, servers[1])
+}
+
+func TestConfiguration_Validate(t *testing.T) {
+	c := New()
+	var notified int
+	c.OnChange("", func(_, _ interface{}) { notified++ })
+	c.Validate(func(c *Configuration) error {
+		if c.GetInt("jupiter.server.http.port") <= 0 {
+			return errors.New("invalid port")
+		}
+		return nil
+	})
+
+	assert.Nil(t, c.LoadLayer("config.toml", PriorityFile, []byte(fileContent), toml.Unmarshal))
+	assert.Equal(t, 1, notified)
+
+	// rejected by validation
+	err := c.LoadLayer("config.toml", PriorityFile, []byte(`[jupiter.server.http]
+	port = -1`), toml.Unmarshal)
+	assert.EqualError(t, err, "validate layer config.toml: invalid port")
+	// rejected by parse
+	assert.Error(t, c.LoadLayer("config.toml", PriorityFile, []byte(`[broken`), toml.Unmarshal))
+
+	assert.Equal(t, 8080, c.GetInt("jupiter.server.http.port"))
+	assert.Equal(t, "info", c.GetString("jupiter.logger.default.level"))
+	assert.Equal(t, 1, notified)
+}
+
+func TestConfiguration_UnmarshalKey(t *testing.T) {
+	c := New()
+	assert.Nil(t, c.SetDefault("jupiter.worker.maxSize", 10))
+	assert.Nil(t, c.LoadFlags([]string{"jupiter.worker.maxSize=20", "jupiter.worker.interval=3s", "jupiter.worker.debug=true"}))
+
+	var config struct {
+		MaxSize  int
+		Interval time.Duration
+		Debug    bool
+	}
+	assert.Nil(t, c.UnmarshalKey("jupiter.worker", &config))
+	assert.Equal(t, 20, config.MaxSize)
+	assert.Equal(t, 3*time.Second, config.Interval)
+	assert.True(t, config.Debug)
+
+	assert.Error(t, c.UnmarshalKey("jupiter.missing", &config))
+	assert.Error(t, c.LoadFlags([]string{"jupiter.worker.maxSize"}))
+}
+
+func TestUnmarshallerFor(t *testing.T) {
+	cases := map[string]string{
+		"config.yaml": "jupiter:\n  logger:\n    level: debug\n",
+		"config.yml":  "jupiter:\n  logger:\n    level: debug\n",
+		"config.json": `{"jupiter": {"logger": {"level": "debug"}}}`,
+		"config.TOML": "[jupiter.logger]\nlevel = \"debug\"\n",
+	}
+	for path, content := range cases {
+		unmarshal, ok := UnmarshallerFor(path)
+		assert.True(t, ok, path)
+		c := New()
+		assert.Nil(t, c.LoadLayer(path, PriorityFile, []byte(content), unmarshal), path)
+		assert.Equal(t, "debug", c.GetString("jupiter.logger.level"), path)
+		assert.Equal(t, path, c.Provenance("jupiter.logger.level"), path)
+	}
+
+	_, ok := UnmarshallerFor("config.ini")
+	assert.False(t, ok)
+}
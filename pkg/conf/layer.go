@@ -0,0 +1,285 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Priorities of the builtin layers, a layer of higher priority overrides the lower ones,
+// layers of the same priority override in the order they are first set.
+const (
+	PriorityDefault = 0
+	PriorityFile    = 100
+	PriorityEnv     = 200
+	PriorityFlag    = 300
+	PrioritySet     = 400
+)
+
+// Names of the builtin layers, a config file layer is named after its path.
+const (
+	LayerDefault = "default"
+	LayerLoad    = "load"
+	LayerEnv     = "env"
+	LayerFlag    = "flag"
+	LayerSet     = "set"
+)
+
+type layer struct {
+	name     string
+	priority int
+	values   map[string]interface{}
+}
+
+// layerValues returns a copy of the values of the layer named name, empty if not found.
+func (c *Configuration) layerValues(name string) map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	values := make(map[string]interface{})
+	for _, l := range c.layers {
+		if l.name == name {
+			mergeMap(values, l.values)
+		}
+	}
+	return values
+}
+
+// SetLayer replaces the values of the layer named name, the layer is inserted with
+// priority if not found. The change is checked by the Validate funcs and applied
+// atomically, then the OnChange funcs of the modified keys are called.
+func (c *Configuration) SetLayer(name string, priority int, values map[string]interface{}) error {
+	c.mu.Lock()
+	layers := make([]*layer, 0, len(c.layers)+1)
+	found := false
+	for _, l := range c.layers {
+		if l.name == name {
+			l = &layer{name: name, priority: l.priority, values: normalizeMap(values)}
+			found = true
+		}
+		layers = append(layers, l)
+	}
+	if !found {
+		layers = append(layers, &layer{name: name, priority: priority, values: normalizeMap(values)})
+		sort.SliceStable(layers, func(i, j int) bool {
+			return layers[i].priority < layers[j].priority
+		})
+	}
+
+	candidate := &Configuration{keyDelim: c.keyDelim, layers: layers, decrypt: c.decrypt}
+	if err := candidate.merge(); err != nil {
+		c.mu.Unlock()
+		return fmt.Errorf("merge layer %s: %w", name, err)
+	}
+	for _, validate := range c.validators {
+		if err := validate(candidate); err != nil {
+			c.mu.Unlock()
+			return fmt.Errorf("validate layer %s: %w", name, err)
+		}
+	}
+	checked, err := c.checkSchemas(candidate.override)
+	if err != nil {
+		err = candidate.maskFields(err)
+		c.mu.Unlock()
+		return fmt.Errorf("validate layer %s: %w", name, err)
+	}
+	for _, key := range checked {
+		delete(c.invalid, key)
+	}
+
+	type change struct {
+		fn       func(old, new interface{})
+		old, new interface{}
+	}
+	var changes []change
+	for _, w := range c.watchers {
+		paths := strings.Split(w.prefix, c.keyDelim)
+		old, new := search(c.override, paths), search(candidate.override, paths)
+		if !reflect.DeepEqual(old, new) {
+			changes = append(changes, change{fn: w.fn, old: old, new: new})
+		}
+	}
+	c.layers, c.override, c.provenance, c.secrets = candidate.layers, candidate.override, candidate.provenance, candidate.secrets
+	c.mu.Unlock()
+
+	for _, ch := range changes {
+		ch.fn(ch.old, ch.new)
+	}
+	return nil
+}
+
+// LoadLayer unmarshals content as the values of the layer named name, see SetLayer.
+func (c *Configuration) LoadLayer(name string, priority int, content []byte, unmarshal Unmarshaller) error {
+	values := make(map[string]interface{})
+	if err := unmarshal(content, &values); err != nil {
+		return fmt.Errorf("load layer %s: %w", name, err)
+	}
+	return c.SetLayer(name, priority, values)
+}
+
+// Layers returns the layer names from the lowest priority to the highest.
+func (c *Configuration) Layers() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.layers))
+	for _, l := range c.layers {
+		names = append(names, l.name)
+	}
+	return names
+}
+
+// EnvName maps a key path to its environment variable,
+// for example jupiter.logger.default.level to JUPITER_LOGGER_DEFAULT_LEVEL.
+func EnvName(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// LoadEnv sets the layer LayerEnv from the environment variables of the keys
+// loaded by layers below it, defaults included, and of the fields of the structs
+// unmarshalled by UnmarshalKey, see EnvName. lookup defaults to os.LookupEnv.
+// lookup is kept to look up the fields of later UnmarshalKey calls.
+func (c *Configuration) LoadEnv(lookup func(string) (string, bool)) error {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	c.mu.Lock()
+	c.envLookup = lookup
+	// a key loaded by a layer keeps its case, such as maxSize
+	keys := make(map[string]string)
+	for _, l := range c.layers {
+		if l.priority >= PriorityEnv {
+			continue
+		}
+		flat := make(map[string]interface{})
+		flatten("", l.values, flat, c.keyDelim)
+		for k := range flat {
+			keys[EnvName(k)] = k
+		}
+	}
+	for key, s := range c.schemas {
+		for _, k := range s.keys(key, c.keyDelim) {
+			if _, ok := keys[EnvName(k)]; !ok {
+				keys[EnvName(k)] = k
+			}
+		}
+	}
+	delim := c.keyDelim
+	c.mu.Unlock()
+
+	values := make(map[string]interface{})
+	for name, key := range keys {
+		if val, ok := lookup(name); ok {
+			paths := strings.Split(key, delim)
+			deepSearch(values, paths[:len(paths)-1])[paths[len(paths)-1]] = val
+		}
+	}
+	return c.SetLayer(LayerEnv, PriorityEnv, values)
+}
+
+// reloadEnv sets the layer LayerEnv again if LoadEnv was called, see LoadEnv.
+func (c *Configuration) reloadEnv() error {
+	c.mu.RLock()
+	lookup := c.envLookup
+	c.mu.RUnlock()
+	if lookup == nil {
+		return nil
+	}
+	return c.LoadEnv(lookup)
+}
+
+// LoadFlags sets the layer LayerFlag from overrides formatted as key=value.
+func (c *Configuration) LoadFlags(overrides []string) error {
+	c.mu.RLock()
+	delim := c.keyDelim
+	c.mu.RUnlock()
+
+	values := make(map[string]interface{})
+	for _, override := range overrides {
+		kv := strings.SplitN(override, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("invalid config override %q, want key=value", override)
+		}
+		paths := strings.Split(kv[0], delim)
+		deepSearch(values, paths[:len(paths)-1])[paths[len(paths)-1]] = kv[1]
+	}
+	return c.SetLayer(LayerFlag, PriorityFlag, values)
+}
+
+// merge rebuilds the merged values and the provenance of every key from the layers,
+// ENC(...) values are decrypted.
+func (c *Configuration) merge() error {
+	override := make(map[string]interface{})
+	flats := make([]map[string]interface{}, len(c.layers))
+	for i, l := range c.layers {
+		mergeMap(override, l.values)
+		flats[i] = make(map[string]interface{})
+		flatten("", l.values, flats[i], c.keyDelim)
+	}
+
+	secrets := make(map[string]struct{})
+	if err := c.decryptMap("", override, secrets); err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{})
+	flatten("", override, values, c.keyDelim)
+	provenance := make(map[string]string, len(values))
+	for key := range values {
+		for i := len(c.layers) - 1; i >= 0; i-- {
+			if _, ok := flats[i][key]; ok {
+				provenance[key] = c.layers[i].name
+				break
+			}
+		}
+	}
+	c.override = override
+	c.provenance = provenance
+	c.secrets = secrets
+	return nil
+}
+
+// mergeMap merges src into dst deeply, maps of src are copied.
+func mergeMap(dst, src map[string]interface{}) {
+	for k, sv := range src {
+		sm, ok := sv.(map[string]interface{})
+		if !ok {
+			dst[k] = sv
+			continue
+		}
+		dm, ok := dst[k].(map[string]interface{})
+		if !ok {
+			dm = make(map[string]interface{}, len(sm))
+			dst[k] = dm
+		}
+		mergeMap(dm, sm)
+	}
+}
+
+// normalizeMap converts the nested map[interface{}]interface{} of yaml to map[string]interface{}.
+func normalizeMap(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		m[k] = normalize(v)
+	}
+	return m
+}
+
+func normalize(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		return normalizeMap(vv)
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, v := range vv {
+			m[fmt.Sprint(k)] = normalize(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range vv {
+			vv[i] = normalize(v)
+		}
+		return vv
+	default:
+		return v
+	}
+}
@@ -0,0 +1,21 @@
+package conf
+
+type (
+	// GetOption ...
+	GetOption func(o *GetOptions)
+	// GetOptions ...
+	GetOptions struct {
+		TagName string
+	}
+)
+
+var defaultGetOptions = GetOptions{
+	TagName: "mapstructure",
+}
+
+// TagName sets the struct tag name of UnmarshalKey
+func TagName(tag string) GetOption {
+	return func(o *GetOptions) {
+		o.TagName = tag
+	}
+}
@@ -0,0 +1,123 @@
+package conf
+
+import (
+	"copy/pkg/secret"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Decrypter decrypts a value written as ENC(...) into its plaintext.
+type Decrypter func(value string) (string, error)
+
+// SetDecrypter sets the decrypter of ENC(...) values. A layer change holding such
+// values is rejected if no decrypter is set or a value fails to decrypt.
+func (c *Configuration) SetDecrypter(decrypt Decrypter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.decrypt = decrypt
+}
+
+// IsSecret reports whether the value of key, or the value key is part of, was decrypted
+func (c *Configuration) IsSecret(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.isSecret(key)
+}
+
+func (c *Configuration) isSecret(key string) bool {
+	for k := range c.secrets {
+		if key == k || strings.HasPrefix(key, k+c.keyDelim) || strings.HasPrefix(key, k+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// SecretValues returns the live plaintexts of the decrypted values, such as to redact
+// them from logs once a reload dropped or rotated some
+func (c *Configuration) SecretValues() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	values := make([]string, 0, len(c.secrets))
+	for key := range c.secrets {
+		switch v := search(c.override, strings.Split(key, c.keyDelim)).(type) {
+		case string:
+			values = append(values, v)
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					values = append(values, s)
+				}
+			}
+		}
+	}
+	return values
+}
+
+// decryptMap replaces the ENC(...) values of m in place and records their keys in secrets,
+// slices are copied since they are shared with the layers.
+func (c *Configuration) decryptMap(prefix string, m map[string]interface{}, secrets map[string]struct{}) error {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + c.keyDelim + k
+		}
+		plain, encrypted, err := c.decryptValue(key, v, secrets)
+		if err != nil {
+			return err
+		}
+		if encrypted {
+			secrets[key] = struct{}{}
+		}
+		m[k] = plain
+	}
+	return nil
+}
+
+func (c *Configuration) decryptValue(key string, v interface{}, secrets map[string]struct{}) (interface{}, bool, error) {
+	switch vv := v.(type) {
+	case string:
+		if !secret.IsEncrypted(vv) {
+			return vv, false, nil
+		}
+		if c.decrypt == nil {
+			return nil, false, fmt.Errorf("%s: no decrypter for encrypted value", key)
+		}
+		plain, err := c.decrypt(vv)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", key, err)
+		}
+		return plain, true, nil
+	case map[string]interface{}:
+		return vv, false, c.decryptMap(key, vv, secrets)
+	case []interface{}:
+		copied := make([]interface{}, len(vv))
+		encrypted := false
+		for i, item := range vv {
+			plain, ok, err := c.decryptValue(fmt.Sprintf("%s[%d]", key, i), item, secrets)
+			if err != nil {
+				return nil, false, err
+			}
+			copied[i] = plain
+			encrypted = encrypted || ok
+		}
+		return copied, encrypted, nil
+	default:
+		return v, false, nil
+	}
+}
+
+// maskFields hides the values of secret fields in a validation error
+func (c *Configuration) maskFields(err error) error {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+	for i, f := range verr.Fields {
+		if c.isSecret(f.Key) {
+			verr.Fields[i] = &FieldError{Key: f.Key, Rule: f.Rule, Value: secret.Mask, Err: errors.New("invalid secret value")}
+		}
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+package conf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfiguration_Secret(t *testing.T) {
+	content := []byte(`
+[jupiter.mysql]
+	user = "root"
+	password = "ENC(k1:cm9vdA==)"
+	slaves = ["ENC(k1:c2xhdmU=)"]
+`)
+	decrypt := func(value string) (string, error) {
+		return "plain-" + strings.TrimSuffix(strings.TrimPrefix(value, "ENC(k1:"), ")"), nil
+	}
+
+	// rejected without a decrypter
+	c := New()
+	assert.Error(t, c.LoadLayer("config.toml", PriorityFile, content, toml.Unmarshal))
+
+	c.SetDecrypter(decrypt)
+	assert.Nil(t, c.LoadLayer("config.toml", PriorityFile, content, toml.Unmarshal))
+	assert.Equal(t, "plain-cm9vdA==", c.GetString("jupiter.mysql.password"))
+	assert.Equal(t, []string{"plain-c2xhdmU="}, c.GetStringSlice("jupiter.mysql.slaves"))
+	assert.True(t, c.IsSecret("jupiter.mysql.password"))
+	assert.True(t, c.IsSecret("jupiter.mysql.slaves[0]"))
+	assert.False(t, c.IsSecret("jupiter.mysql.user"))
+	assert.ElementsMatch(t, []string{"plain-cm9vdA==", "plain-c2xhdmU="}, c.SecretValues())
+
+	dump := c.traverse("_")
+	assert.Equal(t, "******", dump["jupiter_mysql_password"])
+	assert.Equal(t, "******", dump["jupiter_mysql_slaves"])
+	assert.Equal(t, "root", dump["jupiter_mysql_user"])
+
+	// an invalid secret is reported without its value
+	var config struct {
+		Password string `validate:"min=20"`
+	}
+	err := c.UnmarshalKey("jupiter.mysql", &config)
+	assert.True(t, IsValidationError(err))
+	assert.NotContains(t, err.Error(), "plain-")
+	assert.EqualError(t, err, `invalid config, 1 field(s):
+  jupiter.mysql.password: min=20: invalid secret value`)
+}
@@ -0,0 +1,441 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validateTag is the struct tag of validation rules, rules are separated by comma:
+// - required: the value is not zero
+// - min=n, max=n: bounds of a number, of the length of a string, slice or map,
+//   and of a time.Duration written as a duration such as 100ms
+// - oneof=a b c: the value is one of the space separated words
+// - exists: the path exists
+// - writable: the path, or the dir to create it in, can be written
+// Rules other than required skip an empty string.
+const validateTag = "validate"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// FieldError is a field violating a rule, Key is the full key path of the field.
+type FieldError struct {
+	Key   string
+	Rule  string
+	Value interface{}
+	Err   error
+}
+
+// Error ...
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Key, e.Rule, e.Err)
+}
+
+// ValidationError aggregates every invalid field.
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+// Error lists one invalid field per line.
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Fields)+1)
+	lines = append(lines, fmt.Sprintf("invalid config, %d field(s):", len(e.Fields)))
+	for _, f := range e.Fields {
+		lines = append(lines, "  "+f.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// IsValidationError reports whether err is caused by invalid fields only. The
+// RawConfig of a package tolerates it and keeps the defaults of the invalid fields,
+// they are reported by Invalid at startup. Tolerating ErrInvalidKey as well keeps
+// every default without the key.
+func IsValidationError(err error) bool {
+	var verr *ValidationError
+	return errors.As(err, &verr)
+}
+
+// ValidateStruct checks the fields of v by their validate tags, key is the key path of v.
+// Nested structs, slices and maps are checked with their key paths, such as
+// jupiter.server.http.port and jupiter.registry.endpoints[0].
+func ValidateStruct(key string, v interface{}) error {
+	return validateStruct(key, v, defaultGetOptions.TagName)
+}
+
+func validateStruct(key string, v interface{}, tagName string) error {
+	w := &walker{tagName: tagName, visited: make(map[uintptr]bool)}
+	w.walk(key, reflect.ValueOf(v))
+	if len(w.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: w.errs}
+}
+
+type walker struct {
+	tagName string
+	visited map[uintptr]bool
+	errs    []*FieldError
+}
+
+func (w *walker) walk(key string, v reflect.Value) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		if v.Kind() == reflect.Ptr {
+			if w.visited[v.Pointer()] {
+				return
+			}
+			w.visited[v.Pointer()] = true
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name, squash := w.fieldKey(field)
+			if name == "-" {
+				continue
+			}
+			fieldKey := joinKey(key, name)
+			if squash {
+				fieldKey = key
+			}
+			fv := v.Field(i)
+			if rules := field.Tag.Get(validateTag); rules != "" {
+				w.check(fieldKey, fv, rules)
+			}
+			w.walk(fieldKey, fv)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			w.walk(fmt.Sprintf("%s[%d]", key, i), v.Index(i))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			w.walk(joinKey(key, fmt.Sprint(k.Interface())), v.MapIndex(k))
+		}
+	}
+}
+
+// fieldKey returns the config key of field, the tag name of decoding or the field name in lower camel case
+func (w *walker) fieldKey(field reflect.StructField) (name string, squash bool) {
+	tag := strings.Split(field.Tag.Get(w.tagName), ",")
+	for _, opt := range tag[1:] {
+		if opt == "squash" {
+			squash = true
+		}
+	}
+	if tag[0] != "" {
+		return tag[0], squash
+	}
+	return strings.ToLower(field.Name[:1]) + field.Name[1:], squash
+}
+
+func joinKey(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + defaultKeyDelim + name
+}
+
+func (w *walker) check(key string, v reflect.Value, rules string) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v = reflect.Zero(v.Type().Elem())
+			break
+		}
+		v = v.Elem()
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		name, arg := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+		if name != "required" && v.Kind() == reflect.String && v.Len() == 0 {
+			continue
+		}
+		if err := checkRule(name, arg, v); err != nil {
+			w.errs = append(w.errs, &FieldError{Key: key, Rule: rule, Value: v.Interface(), Err: err})
+		}
+	}
+}
+
+func checkRule(name, arg string, v reflect.Value) error {
+	switch name {
+	case "required":
+		if v.IsZero() {
+			return errors.New("is required")
+		}
+	case "min", "max":
+		return checkBound(name, arg, v)
+	case "oneof":
+		value := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(arg) {
+			if value == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of [%s]", value, arg)
+	case "exists":
+		if _, err := os.Stat(v.String()); err != nil {
+			return err
+		}
+	case "writable":
+		return checkWritable(v.String())
+	default:
+		return fmt.Errorf("unknown rule")
+	}
+	return nil
+}
+
+func checkBound(name, arg string, v reflect.Value) error {
+	var value, bound float64
+	var err error
+	switch {
+	case v.Type() == durationType:
+		var d time.Duration
+		if d, err = time.ParseDuration(arg); err == nil {
+			return outOfBound(name, arg, time.Duration(v.Int()), float64(v.Int()), float64(d))
+		}
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		value = float64(v.Int())
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uintptr:
+		value = float64(v.Uint())
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		value = v.Float()
+	case v.Kind() == reflect.String || v.Kind() == reflect.Slice || v.Kind() == reflect.Map || v.Kind() == reflect.Array:
+		value = float64(v.Len())
+	default:
+		return fmt.Errorf("not applicable to %s", v.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("bad rule argument: %w", err)
+	}
+	if bound, err = strconv.ParseFloat(arg, 64); err != nil {
+		return fmt.Errorf("bad rule argument: %w", err)
+	}
+	if v.Kind() == reflect.String || v.Kind() == reflect.Slice || v.Kind() == reflect.Map || v.Kind() == reflect.Array {
+		return outOfBound(name, arg, fmt.Sprintf("length %d", v.Len()), value, bound)
+	}
+	return outOfBound(name, arg, v.Interface(), value, bound)
+}
+
+func outOfBound(name, arg string, show interface{}, value, bound float64) error {
+	if name == "min" && value < bound {
+		return fmt.Errorf("%v is less than %s", show, arg)
+	}
+	if name == "max" && value > bound {
+		return fmt.Errorf("%v is greater than %s", show, arg)
+	}
+	return nil
+}
+
+// checkWritable tries to create a file in path if it is a dir, in its nearest existing parent otherwise
+func checkWritable(path string) error {
+	info, err := os.Stat(path)
+	if err == nil && !info.IsDir() {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+	dir := path
+	for err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+		info, err = os.Stat(dir)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a dir", dir)
+	}
+	f, err := ioutil.TempFile(dir, ".writable")
+	if err != nil {
+		return err
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
+// schema is a struct unmarshalled by a key, with the defaults it had before
+type schema struct {
+	defaults reflect.Value
+	options  GetOptions
+}
+
+// copyStruct returns a copy of the struct rawVal points to, invalid if it is not a struct
+func copyStruct(rawVal interface{}) reflect.Value {
+	v := reflect.ValueOf(rawVal)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	copied := reflect.New(v.Type()).Elem()
+	copied.Set(v)
+	return copied
+}
+
+// keys returns the keys of the fields of the schema of key, the fields of nested
+// structs included, named by their tags or else their names starting in lower case
+func (s schema) keys(key, delim string) []string {
+	return fieldKeys(key, s.defaults.Type(), s.options.TagName, delim, nil, map[reflect.Type]bool{})
+}
+
+func fieldKeys(prefix string, t reflect.Type, tagName, delim string, keys []string, walking map[reflect.Type]bool) []string {
+	if walking[t] {
+		return keys
+	}
+	walking[t] = true
+	defer delete(walking, t)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get(tagName), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name[:1]) + f.Name[1:]
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + delim + name
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}):
+			for _, opt := range tag[1:] {
+				if opt == "squash" {
+					path = prefix
+				}
+			}
+			keys = fieldKeys(path, ft, tagName, delim, keys, walking)
+		case ft.Kind() >= reflect.Bool && ft.Kind() <= reflect.Float64 || ft.Kind() == reflect.String:
+			keys = append(keys, path)
+		}
+	}
+	return keys
+}
+
+// addSchema sets the schema of key, the layer LayerEnv is set again if the
+// environment has variables of new fields, see LoadEnv
+func (c *Configuration) addSchema(key string, s schema) error {
+	c.mu.Lock()
+	old, found := c.schemas[key]
+	c.schemas[key] = s
+	lookup, delim := c.envLookup, c.keyDelim
+	c.mu.Unlock()
+	if lookup == nil || found && old.defaults.Type() == s.defaults.Type() {
+		return nil
+	}
+	for _, k := range s.keys(key, delim) {
+		if _, ok := lookup(EnvName(k)); ok {
+			return c.reloadEnv()
+		}
+	}
+	return nil
+}
+
+// check decodes the value of key in override into a copy of the defaults and validates it,
+// checked is false if key is missing in override
+func (s schema) check(key string, override map[string]interface{}) (checked bool, err error) {
+	value := search(override, strings.Split(key, defaultKeyDelim))
+	if value == nil {
+		return false, nil
+	}
+	ptr := reflect.New(s.defaults.Type())
+	ptr.Elem().Set(s.defaults)
+	if err := decode(value, ptr.Interface(), s.options); err != nil {
+		return true, &ValidationError{Fields: []*FieldError{{Key: key, Rule: "decode", Value: value, Err: err}}}
+	}
+	return true, validateStruct(key, ptr.Interface(), s.options.TagName)
+}
+
+// checkSchemas validates the unmarshalled keys against override, returns the checked keys
+func (c *Configuration) checkSchemas(override map[string]interface{}) ([]string, error) {
+	var checked []string
+	var fields []*FieldError
+	for key, s := range c.schemas {
+		ok, err := s.check(key, override)
+		if ok {
+			checked = append(checked, key)
+		}
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			fields = append(fields, verr.Fields...)
+		}
+	}
+	if len(fields) > 0 {
+		sortFields(fields)
+		return nil, &ValidationError{Fields: fields}
+	}
+	return checked, nil
+}
+
+func (c *Configuration) record(key string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		c.invalid[key] = verr.Fields
+		return
+	}
+	delete(c.invalid, key)
+}
+
+// Invalid returns the invalid fields found by UnmarshalKey in one *ValidationError, nil if none
+func (c *Configuration) Invalid() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var fields []*FieldError
+	for _, f := range c.invalid {
+		fields = append(fields, f...)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	sortFields(fields)
+	return &ValidationError{Fields: fields}
+}
+
+func sortFields(fields []*FieldError) {
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Key < fields[j].Key
+	})
+}
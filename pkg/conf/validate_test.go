@@ -0,0 +1,112 @@
+package conf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+type endpoint struct {
+	Addr string `validate:"required"`
+}
+
+type serverConfig struct {
+	Name      string        `validate:"required"`
+	Port      int           `validate:"min=1,max=65535"`
+	Level     string        `validate:"oneof=debug info warn error"`
+	Timeout   time.Duration `validate:"min=100ms,max=10s"`
+	Tags      []string      `validate:"max=2"`
+	Dir       string        `validate:"writable"`
+	Cert      string        `mapstructure:"certFile" validate:"exists"`
+	Endpoints []endpoint
+}
+
+func validConfig(dir string) serverConfig {
+	return serverConfig{
+		Name:      "http",
+		Port:      8080,
+		Level:     "info",
+		Timeout:   time.Second,
+		Dir:       filepath.Join(dir, "logs", "app"),
+		Endpoints: []endpoint{{Addr: "127.0.0.1:2379"}},
+	}
+}
+
+func TestValidateStruct(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	config := validConfig(dir)
+	assert.Nil(t, ValidateStruct("jupiter.server.http", &config))
+
+	config = serverConfig{
+		Port:      -1,
+		Level:     "verbose",
+		Timeout:   time.Minute,
+		Tags:      []string{"a", "b", "c"},
+		Dir:       filepath.Join(dir, "file", "logs"),
+		Cert:      filepath.Join(dir, "missing.pem"),
+		Endpoints: []endpoint{{Addr: "127.0.0.1:2379"}, {}},
+	}
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0644))
+	err = ValidateStruct("jupiter.server.http", &config)
+	assert.True(t, IsValidationError(err))
+
+	keys := make(map[string]string)
+	for _, f := range err.(*ValidationError).Fields {
+		keys[f.Key] = f.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"jupiter.server.http.name":              "required",
+		"jupiter.server.http.port":              "min=1",
+		"jupiter.server.http.level":             "oneof=debug info warn error",
+		"jupiter.server.http.timeout":           "max=10s",
+		"jupiter.server.http.tags":              "max=2",
+		"jupiter.server.http.dir":               "writable",
+		"jupiter.server.http.certFile":          "exists",
+		"jupiter.server.http.endpoints[1].addr": "required",
+	}, keys)
+}
+
+func TestConfiguration_UnmarshalKeyValidate(t *testing.T) {
+	c := New()
+	assert.Nil(t, c.LoadLayer("config.toml", PriorityFile, []byte(`
+[jupiter.server.http]
+	port = 70000
+[jupiter.server.grpc]
+	name = "grpc"
+	port = 9090
+	level = "trace"
+`), toml.Unmarshal))
+
+	defaults := func() *serverConfig {
+		return &serverConfig{Name: "default", Level: "info", Timeout: time.Second}
+	}
+	http, grpc := defaults(), defaults()
+	err := c.UnmarshalKey("jupiter.server.http", http)
+	assert.True(t, IsValidationError(err))
+	// decoded though invalid
+	assert.Equal(t, 70000, http.Port)
+	assert.True(t, IsValidationError(c.UnmarshalKey("jupiter.server.grpc", grpc)))
+
+	err = c.Invalid()
+	assert.EqualError(t, err, `invalid config, 2 field(s):
+  jupiter.server.grpc.level: oneof=debug info warn error: "trace" is not one of [debug info warn error]
+  jupiter.server.http.port: max=65535: 70000 is greater than 65535`)
+
+	// a fixing change clears the invalid fields
+	assert.Nil(t, c.LoadFlags([]string{"jupiter.server.http.port=8080", "jupiter.server.grpc.level=debug"}))
+	assert.Nil(t, c.Invalid())
+
+	// an invalidating change is rejected with the defaults of the unmarshalled structs
+	err = c.LoadFlags([]string{"jupiter.server.http.port=0"})
+	assert.True(t, IsValidationError(err))
+	assert.Equal(t, 8080, c.GetInt("jupiter.server.http.port"))
+	assert.Nil(t, c.LoadFlags([]string{"jupiter.server.http.port=8081", "jupiter.server.grpc.level=debug"}))
+}
@@ -0,0 +1,101 @@
+package file
+
+import (
+	"copy/pkg/xlog"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DataSourceFile is the scheme of file data source
+const DataSourceFile = "file"
+
+type fileDataSource struct {
+	path     string
+	dir      string
+	realPath string
+	changed  chan struct{}
+	done     chan struct{}
+	once     sync.Once
+	logger   *xlog.Logger
+}
+
+// NewDataSource returns a data source reading the file of path, the file is watched
+// by fsnotify if watch is true. Replacing the file or the symlink to it, as a kubernetes
+// ConfigMap does, is notified as a change too.
+func NewDataSource(path string, watch bool) (*fileDataSource, error) {
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	ds := &fileDataSource{
+		path:    absolutePath,
+		dir:     filepath.Dir(absolutePath),
+		changed: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		logger:  xlog.JupiterLogger,
+	}
+	ds.realPath, _ = filepath.EvalSymlinks(absolutePath)
+	if watch {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			return nil, err
+		}
+		if err := w.Add(ds.dir); err != nil {
+			_ = w.Close()
+			return nil, err
+		}
+		go ds.watch(w)
+	} else {
+		close(ds.changed)
+	}
+	return ds, nil
+}
+
+// ReadConfig ...
+func (fp *fileDataSource) ReadConfig() (content []byte, err error) {
+	return ioutil.ReadFile(fp.path)
+}
+
+// Close stops watching the file
+func (fp *fileDataSource) Close() error {
+	fp.once.Do(func() {
+		close(fp.done)
+	})
+	return nil
+}
+
+// IsConfigChanged is closed after Close, or at once if the file is not watched
+func (fp *fileDataSource) IsConfigChanged() <-chan struct{} {
+	return fp.changed
+}
+
+func (fp *fileDataSource) watch(w *fsnotify.Watcher) {
+	defer close(fp.changed)
+	defer w.Close()
+	// we only care about the config file with the following cases:
+	// 1 - if the config file was modified or created
+	// 2 - if the real path to the config file changed
+	const writeOrCreateMask = fsnotify.Write | fsnotify.Create
+	for {
+		select {
+		case event := <-w.Events:
+			realPath, _ := filepath.EvalSymlinks(fp.path)
+			if (event.Op&writeOrCreateMask != 0 && filepath.Clean(event.Name) == fp.path) ||
+				(realPath != "" && realPath != fp.realPath) {
+				fp.realPath = realPath
+				fp.logger.Debug("config file changed", xlog.FieldMod("file datasource"), xlog.FieldAddr(fp.path), xlog.String("event", event.String()))
+				select {
+				case fp.changed <- struct{}{}:
+				default:
+				}
+			}
+		case err := <-w.Errors:
+			fp.logger.Error("read watch error", xlog.FieldMod("file datasource"), xlog.FieldAddr(fp.path), xlog.FieldErr(err))
+		case <-fp.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataSource_Watch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-datasource")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.toml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("a = 1"), 0644))
+
+	ds, err := NewDataSource(path, true)
+	assert.Nil(t, err)
+	content, err := ds.ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 1", string(content))
+
+	// other files in the dir are ignored
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "other.toml"), []byte("b = 1"), 0644))
+	assert.Nil(t, ioutil.WriteFile(path, []byte("a = 2"), 0644))
+	select {
+	case <-ds.IsConfigChanged():
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not notified")
+	}
+	content, err = ds.ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 2", string(content))
+
+	assert.Nil(t, ds.Close())
+	for range ds.IsConfigChanged() {
+	}
+}
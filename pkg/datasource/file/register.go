@@ -0,0 +1,14 @@
+package file
+
+import (
+	"copy/pkg/conf"
+	"copy/pkg/datasource/manager"
+	"net/url"
+)
+
+func init() {
+	manager.Register(DataSourceFile, func(addr *url.URL, watch bool) (conf.DataSource, error) {
+		// file://./config.toml keeps the relative path in host
+		return NewDataSource(addr.Host+addr.Path, watch)
+	})
+}
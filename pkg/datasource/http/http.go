@@ -0,0 +1,138 @@
+package http
+
+import (
+	"copy/pkg/xlog"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DataSourceHttp is the scheme of http data source
+	DataSourceHttp = "http"
+	// DataSourceHttps is the scheme of https data source
+	DataSourceHttps = "https"
+)
+
+// DefaultInterval is how often a watched http data source is polled
+var DefaultInterval = 10 * time.Second
+
+// httpDataSource reads config with conditional GET by ETag, a watched source polls the
+// address and notifies when the content changes.
+type httpDataSource struct {
+	addr     string
+	client   *http.Client
+	interval time.Duration
+
+	mu      sync.Mutex
+	etag    string
+	content []byte
+
+	changed chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	logger  *xlog.Logger
+}
+
+// NewDataSource returns a data source of addr, polled every interval if watch is true
+func NewDataSource(addr string, watch bool, interval time.Duration) *httpDataSource {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ds := &httpDataSource{
+		addr:     addr,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		interval: interval,
+		changed:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		logger:   xlog.JupiterLogger,
+	}
+	if watch {
+		go ds.watch()
+	} else {
+		close(ds.changed)
+	}
+	return ds
+}
+
+// ReadConfig returns the content of addr, the cached content is returned if addr is not modified
+func (ds *httpDataSource) ReadConfig() ([]byte, error) {
+	content, _, err := ds.fetch()
+	return content, err
+}
+
+// IsConfigChanged ...
+func (ds *httpDataSource) IsConfigChanged() <-chan struct{} {
+	return ds.changed
+}
+
+// Close stops polling
+func (ds *httpDataSource) Close() error {
+	ds.once.Do(func() {
+		close(ds.done)
+	})
+	return nil
+}
+
+// fetch gets addr with If-None-Match, changed is true if the content is modified
+func (ds *httpDataSource) fetch() (content []byte, changed bool, err error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	req, err := http.NewRequest(http.MethodGet, ds.addr, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if ds.etag != "" {
+		req.Header.Set("If-None-Match", ds.etag)
+	}
+	resp, err := ds.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return ds.content, false, nil
+	case http.StatusOK:
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, false, err
+		}
+		// a server without ETag is compared by content, the first read after
+		// a failed one at startup is a change too
+		changed = ds.content == nil || string(body) != string(ds.content)
+		ds.etag = resp.Header.Get("ETag")
+		ds.content = body
+		return body, changed, nil
+	default:
+		return nil, false, fmt.Errorf("get %s: %s", ds.addr, resp.Status)
+	}
+}
+
+func (ds *httpDataSource) watch() {
+	defer close(ds.changed)
+	ticker := time.NewTicker(ds.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_, changed, err := ds.fetch()
+			if err != nil {
+				ds.logger.Error("poll config", xlog.FieldMod("http datasource"), xlog.FieldAddr(ds.addr), xlog.FieldErr(err))
+				continue
+			}
+			if changed {
+				select {
+				case ds.changed <- struct{}{}:
+				default:
+				}
+			}
+		case <-ds.done:
+			return
+		}
+	}
+}
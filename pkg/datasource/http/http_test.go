@@ -0,0 +1,104 @@
+package http
+
+import (
+	"copy/pkg/datasource/manager"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type configServer struct {
+	mu       sync.Mutex
+	content  string
+	requests int
+	matched  int
+	query    string
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	s.query = r.URL.RawQuery
+	sum := md5.Sum([]byte(s.content))
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		s.matched++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	_, _ = w.Write([]byte(s.content))
+}
+
+func (s *configServer) set(content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.content = content
+}
+
+func TestDataSource(t *testing.T) {
+	cs := &configServer{content: "a = 1"}
+	srv := httptest.NewServer(cs)
+	defer srv.Close()
+
+	ds := NewDataSource(srv.URL+"/config.toml", true, 10*time.Millisecond)
+	defer ds.Close()
+	content, err := ds.ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 1", string(content))
+
+	// not modified
+	content, err = ds.ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 1", string(content))
+	cs.mu.Lock()
+	assert.True(t, cs.matched > 0)
+	cs.mu.Unlock()
+
+	cs.set("a = 2")
+	select {
+	case <-ds.IsConfigChanged():
+	case <-time.After(time.Second):
+		t.Fatal("change not notified")
+	}
+	content, err = ds.ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 2", string(content))
+}
+
+func TestDataSource_Status(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	ds := NewDataSource(srv.URL, false, 0)
+	_, err := ds.ReadConfig()
+	assert.Error(t, err)
+	_, ok := <-ds.IsConfigChanged()
+	assert.False(t, ok)
+}
+
+func TestDataSource_Interval(t *testing.T) {
+	cs := &configServer{content: "a = 1"}
+	srv := httptest.NewServer(cs)
+	defer srv.Close()
+
+	ds, err := manager.NewDataSource(srv.URL+"/config.toml?env=prod&interval=20ms", false)
+	assert.Nil(t, err)
+	defer ds.Close()
+	content, err := ds.ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 1", string(content))
+	cs.mu.Lock()
+	assert.Equal(t, "env=prod", cs.query)
+	cs.mu.Unlock()
+
+	_, err = manager.NewDataSource(srv.URL+"/config.toml?interval=soon", false)
+	assert.Error(t, err)
+}
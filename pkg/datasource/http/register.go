@@ -0,0 +1,29 @@
+package http
+
+import (
+	"copy/pkg/conf"
+	"copy/pkg/datasource/manager"
+	"net/url"
+	"time"
+)
+
+func init() {
+	// the query interval sets the poll interval, such as https://config.example.com/app.toml?interval=30s
+	dataSourceCreator := func(addr *url.URL, watch bool) (conf.DataSource, error) {
+		var interval time.Duration
+		query := addr.Query()
+		if v := query.Get("interval"); v != "" {
+			var err error
+			if interval, err = time.ParseDuration(v); err != nil {
+				return nil, err
+			}
+		}
+		// the interval is not sent to the config server
+		u := *addr
+		query.Del("interval")
+		u.RawQuery = query.Encode()
+		return NewDataSource(u.String(), watch, interval), nil
+	}
+	manager.Register(DataSourceHttp, dataSourceCreator)
+	manager.Register(DataSourceHttps, dataSourceCreator)
+}
@@ -0,0 +1,104 @@
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DataSourceKV is the scheme of key-value store data source, the address is
+// kv://<store>/<key>, such as kv://memory/app/config.toml
+const DataSourceKV = "kv"
+
+// ErrNotFound is returned by Store.Get of a missing key
+var ErrNotFound = errors.New("key not found")
+
+// Store is a key-value store holding config contents.
+type Store interface {
+	// Get returns the value of key
+	Get(key string) ([]byte, error)
+	// Watch notifies every change of key until stop is closed
+	Watch(key string, stop <-chan struct{}) <-chan struct{}
+}
+
+var (
+	mu     sync.RWMutex
+	stores = make(map[string]Store)
+)
+
+// RegisterStore registers store by name, the host of a kv address
+func RegisterStore(name string, store Store) {
+	mu.Lock()
+	defer mu.Unlock()
+	stores[name] = store
+}
+
+func getStore(name string) (Store, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	store, ok := stores[name]
+	if !ok {
+		return nil, fmt.Errorf("kv store %s not registered", name)
+	}
+	return store, nil
+}
+
+type kvDataSource struct {
+	store   Store
+	key     string
+	changed chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewDataSource returns a data source of key in store, changes are notified if watch is true
+func NewDataSource(store Store, key string, watch bool) *kvDataSource {
+	ds := &kvDataSource{
+		store:   store,
+		key:     key,
+		changed: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if watch {
+		go ds.watch(store.Watch(key, ds.done))
+	} else {
+		close(ds.changed)
+	}
+	return ds
+}
+
+// ReadConfig ...
+func (ds *kvDataSource) ReadConfig() ([]byte, error) {
+	return ds.store.Get(ds.key)
+}
+
+// IsConfigChanged ...
+func (ds *kvDataSource) IsConfigChanged() <-chan struct{} {
+	return ds.changed
+}
+
+// Close stops watching the key
+func (ds *kvDataSource) Close() error {
+	ds.once.Do(func() {
+		close(ds.done)
+	})
+	return nil
+}
+
+func (ds *kvDataSource) watch(events <-chan struct{}) {
+	defer close(ds.changed)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+			select {
+			case ds.changed <- struct{}{}:
+			default:
+			}
+		case <-ds.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package kv
+
+import (
+	"copy/pkg/datasource/manager"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waitChanged(t *testing.T, changed <-chan struct{}) (ok bool) {
+	t.Helper()
+	select {
+	case _, ok = <-changed:
+		return ok
+	case <-time.After(time.Second):
+		t.Fatal("no change event")
+		return false
+	}
+}
+
+func TestDataSource(t *testing.T) {
+	store := NewMemoryStore()
+	store.Put("app/config.toml", []byte("a = 1"))
+	ds := NewDataSource(store, "app/config.toml", true)
+	defer ds.Close()
+
+	content, err := ds.ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 1", string(content))
+
+	store.Put("app/config.toml", []byte("a = 2"))
+	assert.True(t, waitChanged(t, ds.IsConfigChanged()))
+	content, err = ds.ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 2", string(content))
+
+	// a deleted key is notified and missing
+	store.Delete("app/config.toml")
+	assert.True(t, waitChanged(t, ds.IsConfigChanged()))
+	_, err = ds.ReadConfig()
+	assert.Equal(t, ErrNotFound, err)
+
+	// other keys are not notified
+	store.Put("app/other.toml", []byte("b = 1"))
+	select {
+	case <-ds.IsConfigChanged():
+		t.Fatal("change event of another key")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestDataSource_Close(t *testing.T) {
+	store := NewMemoryStore()
+	ds := NewDataSource(store, "app/config.toml", true)
+	assert.Nil(t, ds.Close())
+	assert.Nil(t, ds.Close())
+	assert.False(t, waitChanged(t, ds.IsConfigChanged()))
+
+	// the watcher is removed from the store
+	assert.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return len(store.watchers["app/config.toml"]) == 0
+	}, time.Second, time.Millisecond)
+	store.Put("app/config.toml", []byte("a = 1"))
+
+	// a data source not watched is never notified
+	ds = NewDataSource(store, "app/config.toml", false)
+	defer ds.Close()
+	assert.False(t, waitChanged(t, ds.IsConfigChanged()))
+}
+
+func TestRegister(t *testing.T) {
+	store := NewMemoryStore()
+	store.Put("app/config.toml", []byte("a = 1"))
+	RegisterStore("test", store)
+
+	ds, err := manager.NewDataSource("kv://test/app/config.toml", false)
+	assert.Nil(t, err)
+	defer ds.Close()
+	content, err := ds.ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 1", string(content))
+
+	ds, err = manager.NewDataSource("kv://test/app/missing.toml", false)
+	assert.Nil(t, err)
+	defer ds.Close()
+	_, err = ds.ReadConfig()
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = manager.NewDataSource("kv://unknown/app/config.toml", false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,79 @@
+package kv
+
+import (
+	"sync"
+)
+
+// MemoryStore is an in-memory Store, it stands in for a real key-value store in tests.
+type MemoryStore struct {
+	mu       sync.Mutex
+	values   map[string][]byte
+	watchers map[string][]chan struct{}
+}
+
+// NewMemoryStore ...
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		values:   make(map[string][]byte),
+		watchers: make(map[string][]chan struct{}),
+	}
+}
+
+// Get ...
+func (s *MemoryStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+// Put sets the value of key and notifies its watchers
+func (s *MemoryStore) Put(key string, value []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.notify(key)
+}
+
+// Delete deletes key and notifies its watchers
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	s.notify(key)
+}
+
+// Watch ...
+func (s *MemoryStore) Watch(key string, stop <-chan struct{}) <-chan struct{} {
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.watchers[key] = append(s.watchers[key], ch)
+	s.mu.Unlock()
+
+	go func() {
+		<-stop
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		watchers := s.watchers[key]
+		for i, w := range watchers {
+			if w == ch {
+				s.watchers[key] = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func (s *MemoryStore) notify(key string) {
+	for _, ch := range s.watchers[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package kv
+
+import (
+	"copy/pkg/conf"
+	"copy/pkg/datasource/manager"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	manager.Register(DataSourceKV, func(addr *url.URL, watch bool) (conf.DataSource, error) {
+		store, err := getStore(addr.Host)
+		if err != nil {
+			return nil, err
+		}
+		return NewDataSource(store, strings.TrimPrefix(addr.Path, "/"), watch), nil
+	})
+}
@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"copy/pkg/conf"
+	"copy/pkg/xlog"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/douyu/jupiter/pkg/ecode"
+)
+
+// cachedDataSource keeps a local copy of every content read from a remote data source,
+// the copy is read instead when the remote source fails before its first successful read.
+type cachedDataSource struct {
+	conf.DataSource
+	path   string
+	mu     sync.Mutex
+	loaded bool
+	logger *xlog.Logger
+}
+
+// Cache wraps ds to keep a local copy of its content in the file of path
+func Cache(ds conf.DataSource, path string) conf.DataSource {
+	return &cachedDataSource{DataSource: ds, path: path, logger: xlog.JupiterLogger}
+}
+
+// CachePath returns the cache file of configAddr in dir, named by the hash and the extension of configAddr
+func CachePath(dir string, configAddr string) string {
+	sum := sha1.Sum([]byte(configAddr))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+path.Ext(Path(configAddr)))
+}
+
+// ReadConfig ...
+func (c *cachedDataSource) ReadConfig() ([]byte, error) {
+	content, err := c.DataSource.ReadConfig()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		if c.loaded {
+			return nil, err
+		}
+		cached, cerr := ioutil.ReadFile(c.path)
+		if cerr != nil {
+			return nil, err
+		}
+		c.logger.Warn("read config from cache", xlog.FieldMod(ecode.ModConfig), xlog.FieldAddr(c.path), xlog.FieldErr(err))
+		return cached, nil
+	}
+	c.loaded = true
+	if werr := writeFile(c.path, content); werr != nil {
+		c.logger.Warn("write config cache", xlog.FieldMod(ecode.ModConfig), xlog.FieldAddr(c.path), xlog.FieldErr(werr))
+	}
+	return content, nil
+}
+
+// writeFile writes content into a temp file renamed to path, so a crash never leaves a partial cache
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
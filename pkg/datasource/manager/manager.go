@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"copy/pkg/conf"
+	"errors"
+	"net/url"
+	"sync"
+)
+
+var (
+	//ErrConfigAddr not config
+	ErrConfigAddr = errors.New("no config... ")
+	// ErrInvalidDataSource defines an error that the scheme has been registered
+	ErrInvalidDataSource = errors.New("invalid data source, please make sure the scheme has been registered")
+
+	mu       sync.RWMutex
+	registry = make(map[string]DataSourceCreatorFunc)
+)
+
+// DefaultScheme is the scheme of a config address without one, such as a local path
+const DefaultScheme = "file"
+
+// DataSourceCreatorFunc creates the data source of addr, the source notifies changes if watch is true
+type DataSourceCreatorFunc func(addr *url.URL, watch bool) (conf.DataSource, error)
+
+// Register registers the creator of scheme, a former creator of scheme is replaced
+func Register(scheme string, creator DataSourceCreatorFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[scheme] = creator
+}
+
+// Scheme returns the scheme of configAddr, DefaultScheme for a local path
+func Scheme(configAddr string) string {
+	urlObj, err := url.Parse(configAddr)
+	// a scheme of one letter is a windows volume
+	if err == nil && len(urlObj.Scheme) > 1 {
+		return urlObj.Scheme
+	}
+	return DefaultScheme
+}
+
+// Path returns the path of configAddr without scheme, host and query,
+// its extension decides the format of the config content
+func Path(configAddr string) string {
+	urlObj, err := url.Parse(configAddr)
+	if err != nil || len(urlObj.Scheme) <= 1 {
+		return configAddr
+	}
+	return urlObj.Path
+}
+
+// NewDataSource creates the data source of configAddr by the creator registered for its scheme
+func NewDataSource(configAddr string, watch bool) (conf.DataSource, error) {
+	if configAddr == "" {
+		return nil, ErrConfigAddr
+	}
+	scheme := Scheme(configAddr)
+	urlObj, err := url.Parse(configAddr)
+	if err != nil || len(urlObj.Scheme) <= 1 {
+		urlObj = &url.URL{Scheme: DefaultScheme, Path: configAddr}
+	}
+
+	mu.RLock()
+	creatorFunc, exist := registry[scheme]
+	mu.RUnlock()
+	if !exist {
+		return nil, ErrInvalidDataSource
+	}
+	return creatorFunc(urlObj, watch)
+}
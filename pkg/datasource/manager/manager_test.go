@@ -0,0 +1,90 @@
+package manager_test
+
+import (
+	"copy/pkg/datasource/kv"
+	"copy/pkg/datasource/manager"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheme(t *testing.T) {
+	assert.Equal(t, "file", manager.Scheme("config/app.toml"))
+	assert.Equal(t, "file", manager.Scheme(`C:\config\app.toml`))
+	assert.Equal(t, "file", manager.Scheme("file:///etc/app.toml"))
+	assert.Equal(t, "https", manager.Scheme("https://config.example.com/app.toml?interval=5s"))
+	assert.Equal(t, "kv", manager.Scheme("kv://memory/app/config.toml"))
+
+	assert.Equal(t, "/app.toml", manager.Path("https://config.example.com/app.toml?interval=5s"))
+	assert.Equal(t, "config/app.toml", manager.Path("config/app.toml"))
+}
+
+func TestNewDataSource(t *testing.T) {
+	_, err := manager.NewDataSource("", false)
+	assert.Equal(t, manager.ErrConfigAddr, err)
+	_, err = manager.NewDataSource("unknown://app.toml", false)
+	assert.Equal(t, manager.ErrInvalidDataSource, err)
+
+	store := kv.NewMemoryStore()
+	kv.RegisterStore("memory", store)
+	store.Put("app/config.toml", []byte("a = 1"))
+
+	ds, err := manager.NewDataSource("kv://memory/app/config.toml", true)
+	assert.Nil(t, err)
+	defer ds.Close()
+	content, err := ds.ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 1", string(content))
+
+	store.Put("app/config.toml", []byte("a = 2"))
+	select {
+	case <-ds.IsConfigChanged():
+	case <-time.After(time.Second):
+		t.Fatal("change not notified")
+	}
+	content, err = ds.ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 2", string(content))
+}
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-cache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store := kv.NewMemoryStore()
+	kv.RegisterStore("cache", store)
+	addr := "kv://cache/app/config.toml"
+	path := manager.CachePath(dir, addr)
+	assert.Equal(t, ".toml", filepath.Ext(path))
+
+	// unreachable without cache
+	ds, err := manager.NewDataSource(addr, false)
+	assert.Nil(t, err)
+	_, err = manager.Cache(ds, path).ReadConfig()
+	assert.Equal(t, kv.ErrNotFound, err)
+
+	store.Put("app/config.toml", []byte("a = 1"))
+	content, err := manager.Cache(ds, path).ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 1", string(content))
+
+	// unreachable at startup, read from cache
+	store.Delete("app/config.toml")
+	cached := manager.Cache(ds, path)
+	content, err = cached.ReadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "a = 1", string(content))
+
+	// failed after loaded
+	store.Put("app/config.toml", []byte("a = 2"))
+	_, err = cached.ReadConfig()
+	assert.Nil(t, err)
+	store.Delete("app/config.toml")
+	_, err = cached.ReadConfig()
+	assert.Equal(t, kv.ErrNotFound, err)
+}
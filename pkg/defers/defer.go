@@ -10,6 +10,6 @@ func Register(fns ...func() error) {
 	globalDefers.Push(fns...)
 }
 
-func Clean() {
-	globalDefers.Clean()
+func Clean() error {
+	return globalDefers.Clean()
 }
@@ -39,6 +39,23 @@ func init() {
 	testing.Init()
 }
 
+// NewFlagSet returns a flagset of the default flags parsing args instead of
+// os.Args, an error is returned by Parse instead of exiting.
+func NewFlagSet(name string, args []string) *FlagSet {
+	return &FlagSet{
+		FlagSet:  flag.NewFlagSet(name, flag.ContinueOnError),
+		flags:    append([]Flag(nil), defaultFlags...),
+		actions:  make(map[string]func(string, *FlagSet)),
+		environs: make(map[string]string),
+		args:     append([]string{}, args...),
+	}
+}
+
+// CommandLine returns the flagset of os.Args used by the package functions.
+func CommandLine() *FlagSet {
+	return flagset
+}
+
 // Flag ...
 type (
 	// Flag defines application flag.
@@ -52,6 +69,8 @@ type (
 		flags    []Flag
 		actions  map[string]func(string, *FlagSet)
 		environs map[string]string
+		// args are parsed instead of os.Args if not nil
+		args []string
 	}
 )
 
@@ -85,7 +104,7 @@ func (fs *FlagSet) Lookup(name string) *flag.Flag {
 	if flag != nil {
 		if flag.Value.String() == "" {
 			if env, ok := fs.environs[name]; ok {
-				flag.Value.Set(env)
+				setEnv(flag.Value, env)
 			}
 		}
 		if flag.Value.String() == "" {
@@ -95,6 +114,19 @@ func (fs *FlagSet) Lookup(name string) *flag.Flag {
 	return flag
 }
 
+// setEnv sets value from an environment variable,
+// the values of a string slice are separated by comma.
+func setEnv(value flag.Value, env string) {
+	slice, ok := value.(*stringSlice)
+	if !ok {
+		value.Set(env)
+		return
+	}
+	for _, v := range strings.Split(env, ",") {
+		slice.Set(strings.TrimSpace(v))
+	}
+}
+
 // Parse parses provided flagset.
 func (fs *FlagSet) Parse() error {
 	if fs.Parsed() {
@@ -104,7 +136,11 @@ func (fs *FlagSet) Parse() error {
 		f.Apply(fs)
 	}
 
-	if err := fs.FlagSet.Parse(os.Args[1:]); err != nil {
+	args := fs.args
+	if args == nil {
+		args = os.Args[1:]
+	}
+	if err := fs.FlagSet.Parse(args); err != nil {
 		return err
 	}
 
@@ -208,6 +244,31 @@ func (fs *FlagSet) Uint(name string) uint64 {
 	return ret
 }
 
+// StringSliceE parses string slice flag of the flagset with error returned.
+func StringSliceE(name string) ([]string, error) { return flagset.StringSliceE(name) }
+
+// StringSliceE parses string slice flag of provided flagset with error returned.
+func (fs *FlagSet) StringSliceE(name string) ([]string, error) {
+	flag := fs.Lookup(name)
+	if flag != nil {
+		if value, ok := flag.Value.(*stringSlice); ok {
+			return value.values, nil
+		}
+		return nil, fmt.Errorf("not a string slice flag: %s", name)
+	}
+
+	return nil, fmt.Errorf("undefined flag name: %s", name)
+}
+
+// StringSlice parses string slice flag of the flagset.
+func StringSlice(name string) []string { return flagset.StringSlice(name) }
+
+// StringSlice parses string slice flag of provided flagset.
+func (fs *FlagSet) StringSlice(name string) []string {
+	ret, _ := fs.StringSliceE(name)
+	return ret
+}
+
 // Float64E parses int flag of the flagset with error returned.
 func Float64E(name string) (float64, error) { return flagset.Float64E(name) }
 
@@ -339,3 +400,58 @@ func (f *Float64Flag) Apply(set *FlagSet) {
 		set.actions[field] = f.Action
 	}
 }
+
+// StringSliceFlag is a repeatable string flag implements of Flag interface,
+// each occurrence appends a value, the first one replaces the default.
+// The values of EnvVar are separated by comma.
+type StringSliceFlag struct {
+	Name     string
+	Usage    string
+	EnvVar   string
+	Default  []string
+	Variable *[]string
+	Action   func(string, *FlagSet)
+}
+
+// Apply implements of Flag Apply function.
+func (f *StringSliceFlag) Apply(set *FlagSet) {
+	for _, field := range strings.Split(f.Name, ",") {
+		field = strings.TrimSpace(field)
+		value := &stringSlice{values: append([]string(nil), f.Default...), variable: f.Variable}
+		if f.Variable != nil {
+			*f.Variable = value.values
+		}
+		set.FlagSet.Var(value, field, f.Usage)
+		set.actions[field] = f.Action
+		set.environs[field] = os.Getenv(f.EnvVar)
+	}
+}
+
+type stringSlice struct {
+	values   []string
+	variable *[]string
+	changed  bool
+}
+
+func (s *stringSlice) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(s.values, ",")
+}
+
+// Set appends value, an empty value is ignored.
+func (s *stringSlice) Set(value string) error {
+	if value == "" {
+		return nil
+	}
+	if !s.changed {
+		s.values = nil
+		s.changed = true
+	}
+	s.values = append(s.values, value)
+	if s.variable != nil {
+		*s.variable = s.values
+	}
+	return nil
+}
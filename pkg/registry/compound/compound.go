@@ -0,0 +1,331 @@
+package compound
+
+import (
+	"context"
+	"copy/pkg/registry"
+	"copy/pkg/server"
+	"copy/pkg/util/xtime"
+	"copy/pkg/xlog"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+const modRegistry = "registry.compound"
+
+// ErrClosed is returned by RegisterService after Close
+var ErrClosed = errors.New("registry closed")
+
+// Registry fans out to registries. A registration failed in a registry is retried
+// with backoff in the background, a registered service is registered again every
+// TTL as a heartbeat, until it is unregistered or the registry is closed.
+type Registry struct {
+	config     *Config
+	registries []registry.Registry
+
+	mu      sync.Mutex
+	keepers map[string][]*keeper
+	closed  bool
+}
+
+// New fans out to registries with the default config.
+func New(registries ...registry.Registry) *Registry {
+	return DefaultConfig().Build(registries...)
+}
+
+func newRegistry(config *Config, registries []registry.Registry) *Registry {
+	return &Registry{
+		config:     config,
+		registries: registries,
+		keepers:    make(map[string][]*keeper),
+	}
+}
+
+// RegisterService registers info in every registry, the errors of the first attempts
+// are returned while failed registrations keep retrying. Registering a service of
+// the same label again replaces the former registration.
+func (c *Registry) RegisterService(ctx context.Context, info *server.ServiceInfo) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	label := info.Label()
+	c.stopKeepers(label)
+	c.mu.Unlock()
+
+	// registries are called unlocked, a slow one holds up no other service
+	copied := *info
+	errs := make([]error, len(c.registries))
+	var wg sync.WaitGroup
+	for i, r := range c.registries {
+		wg.Add(1)
+		go func(i int, r registry.Registry) {
+			defer wg.Done()
+			errs[i] = c.register(ctx, r, &copied)
+		}(i, r)
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	// the keepers of a concurrent registration of label are replaced
+	c.stopKeepers(label)
+	var err error
+	keepers := make([]*keeper, 0, len(c.registries))
+	for i, r := range c.registries {
+		if errs[i] != nil {
+			err = multierr.Append(err, fmt.Errorf("register %s in %T: %w", label, r, errs[i]))
+			c.config.logger.Warn("register service, retrying", xlog.FieldMod(modRegistry), xlog.FieldAddr(label), xlog.String("registry", fmt.Sprintf("%T", r)), xlog.FieldErr(errs[i]))
+		}
+		k := &keeper{registry: r, info: &copied, stop: make(chan struct{}), done: make(chan struct{})}
+		go c.keep(k, errs[i] == nil)
+		keepers = append(keepers, k)
+	}
+	c.keepers[label] = keepers
+	return err
+}
+
+// UnregisterService stops retrying and heartbeats of info, and unregisters it from every registry.
+func (c *Registry) UnregisterService(ctx context.Context, info *server.ServiceInfo) error {
+	c.mu.Lock()
+	c.stopKeepers(info.Label())
+	c.mu.Unlock()
+
+	return c.fanOut(func(r registry.Registry) error {
+		ctx, cancel := c.withTimeout(ctx)
+		defer cancel()
+		if err := r.UnregisterService(ctx, info); err != nil {
+			return fmt.Errorf("unregister %s in %T: %w", info.Label(), r, err)
+		}
+		return nil
+	})
+}
+
+// ListServices lists the services of every registry, a service found in several
+// registries is listed once.
+func (c *Registry) ListServices(ctx context.Context, name string, scheme string) ([]*server.ServiceInfo, error) {
+	var mu sync.Mutex
+	var services = make([]*server.ServiceInfo, 0)
+	seen := make(map[string]bool)
+	err := c.fanOut(func(r registry.Registry) error {
+		infos, err := r.ListServices(ctx, name, scheme)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, info := range infos {
+			if !seen[info.Label()] {
+				seen[info.Label()] = true
+				services = append(services, info)
+			}
+		}
+		return nil
+	})
+	return services, err
+}
+
+// WatchServices merges the endpoints watched in every registry, each update sends
+// the union of the latest endpoints of all registries. The channel is closed once
+// every watch ends.
+func (c *Registry) WatchServices(ctx context.Context, name string, scheme string) (chan registry.Endpoints, error) {
+	watches := make([]chan registry.Endpoints, 0, len(c.registries))
+	for _, r := range c.registries {
+		ch, err := r.WatchServices(ctx, name, scheme)
+		if err != nil {
+			return nil, fmt.Errorf("watch %s in %T: %w", name, r, err)
+		}
+		watches = append(watches, ch)
+	}
+
+	type update struct {
+		index     int
+		endpoints registry.Endpoints
+	}
+	updates := make(chan update)
+	var wg sync.WaitGroup
+	for i, ch := range watches {
+		wg.Add(1)
+		go func(i int, ch chan registry.Endpoints) {
+			defer wg.Done()
+			for endpoints := range ch {
+				select {
+				case updates <- update{i, endpoints}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(i, ch)
+	}
+	go func() {
+		wg.Wait()
+		close(updates)
+	}()
+
+	merged := make(chan registry.Endpoints)
+	go func() {
+		defer close(merged)
+		latest := make([]*registry.Endpoints, len(watches))
+		for u := range updates {
+			endpoints := u.endpoints
+			latest[u.index] = &endpoints
+			select {
+			case merged <- union(latest):
+			case <-ctx.Done():
+				for range updates {
+				}
+				return
+			}
+		}
+	}()
+	return merged, nil
+}
+
+// Close stops retrying and heartbeats, and closes every registry.
+func (c *Registry) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	for label := range c.keepers {
+		c.stopKeepers(label)
+	}
+	c.mu.Unlock()
+
+	return c.fanOut(func(r registry.Registry) error {
+		return r.Close()
+	})
+}
+
+// Detach stops retrying and heartbeats like Close, but leaves the services registered
+// and the registries open, for a process whose services are served by a new one.
+func (c *Registry) Detach() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for label := range c.keepers {
+		c.stopKeepers(label)
+	}
+}
+
+func (c *Registry) fanOut(fn func(r registry.Registry) error) error {
+	var mu sync.Mutex
+	var errs error
+	var wg sync.WaitGroup
+	for _, r := range c.registries {
+		wg.Add(1)
+		go func(r registry.Registry) {
+			defer wg.Done()
+			if err := fn(r); err != nil {
+				mu.Lock()
+				errs = multierr.Append(errs, err)
+				mu.Unlock()
+			}
+		}(r)
+	}
+	wg.Wait()
+	return errs
+}
+
+func (c *Registry) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.config.Timeout > 0 {
+		return context.WithTimeout(ctx, c.config.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func (c *Registry) register(ctx context.Context, r registry.Registry, info *server.ServiceInfo) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return r.RegisterService(ctx, info)
+}
+
+// stopKeepers stops the keepers of label and waits for them, c.mu is held
+func (c *Registry) stopKeepers(label string) {
+	for _, k := range c.keepers[label] {
+		close(k.stop)
+		<-k.done
+	}
+	delete(c.keepers, label)
+}
+
+// keeper retries and heartbeats the registration of a service in a registry
+type keeper struct {
+	registry registry.Registry
+	info     *server.ServiceInfo
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func (c *Registry) keep(k *keeper, registered bool) {
+	defer close(k.done)
+	var attempt int
+	for {
+		wait := c.config.TTL
+		if !registered {
+			wait = c.backoff(attempt)
+			attempt++
+		} else if wait <= 0 {
+			return
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-k.stop:
+			timer.Stop()
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-k.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		err := c.register(ctx, k.registry, k.info)
+		cancel()
+		switch {
+		case err != nil:
+			if registered {
+				attempt = 0
+			}
+			registered = false
+			c.config.logger.Warn("register service, retrying", xlog.FieldMod(modRegistry), xlog.FieldAddr(k.info.Label()), xlog.String("registry", fmt.Sprintf("%T", k.registry)), xlog.Int("attempt", attempt), xlog.FieldErr(err))
+		case !registered:
+			registered = true
+			c.config.logger.Info("register service", xlog.FieldMod(modRegistry), xlog.FieldAddr(k.info.Label()), xlog.String("registry", fmt.Sprintf("%T", k.registry)), xlog.Int("attempt", attempt))
+		}
+	}
+}
+
+func (c *Registry) backoff(attempt int) time.Duration {
+	return xtime.Backoff{
+		Min:        c.config.MinBackoff,
+		Max:        c.config.MaxBackoff,
+		Multiplier: c.config.Multiplier,
+		Jitter:     c.config.Jitter,
+	}.Duration(attempt)
+}
+
+func union(latest []*registry.Endpoints) registry.Endpoints {
+	merged := registry.Endpoints{
+		Nodes:           make(map[string]server.ServiceInfo),
+		RouteConfigs:    make(map[string]registry.RouteConfig),
+		ConsumerConfigs: make(map[string]registry.ConsumerConfig),
+		ProviderConfigs: make(map[string]registry.ProviderConfig),
+	}
+	for _, endpoints := range latest {
+		if endpoints != nil {
+			endpoints.DeepCopyInfo(&merged)
+		}
+	}
+	return merged
+}
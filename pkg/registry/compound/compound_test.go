@@ -0,0 +1,169 @@
+package compound
+
+import (
+	"context"
+	"copy/pkg/registry"
+	"copy/pkg/server"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRegistry fails the first failures registrations
+type testRegistry struct {
+	registry.Nop
+	mu         sync.Mutex
+	failures   int
+	registers  int
+	registered map[string]bool
+	services   []*server.ServiceInfo
+}
+
+func newTestRegistry(failures int) *testRegistry {
+	return &testRegistry{failures: failures, registered: make(map[string]bool)}
+}
+
+func (r *testRegistry) RegisterService(_ context.Context, info *server.ServiceInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registers++
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("unavailable")
+	}
+	r.registered[info.Label()] = true
+	return nil
+}
+
+func (r *testRegistry) UnregisterService(_ context.Context, info *server.ServiceInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.registered, info.Label())
+	return nil
+}
+
+func (r *testRegistry) ListServices(context.Context, string, string) ([]*server.ServiceInfo, error) {
+	return r.services, nil
+}
+
+func (r *testRegistry) state() (registers int, registered bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.registers, r.registered["grpc://127.0.0.1:9090"]
+}
+
+func testConfig() *Config {
+	config := DefaultConfig()
+	config.MinBackoff = 10 * time.Millisecond
+	config.MaxBackoff = 20 * time.Millisecond
+	config.TTL = 0
+	return config
+}
+
+var info = &server.ServiceInfo{Name: "demo", Scheme: "grpc", Address: "127.0.0.1:9090"}
+
+func TestRegistry_Retry(t *testing.T) {
+	ok, flaky := newTestRegistry(0), newTestRegistry(2)
+	c := testConfig().Build(ok, flaky)
+
+	err := c.RegisterService(context.Background(), info)
+	assert.Error(t, err)
+	_, registered := ok.state()
+	assert.True(t, registered)
+
+	assert.Eventually(t, func() bool {
+		_, registered := flaky.state()
+		return registered
+	}, time.Second, 5*time.Millisecond)
+	registers, _ := flaky.state()
+	assert.Equal(t, 3, registers)
+
+	assert.Nil(t, c.UnregisterService(context.Background(), info))
+	_, registered = flaky.state()
+	assert.False(t, registered)
+	assert.Nil(t, c.Close())
+	assert.Equal(t, ErrClosed, c.RegisterService(context.Background(), info))
+}
+
+func TestRegistry_Heartbeat(t *testing.T) {
+	r := newTestRegistry(0)
+	config := testConfig()
+	config.TTL = 10 * time.Millisecond
+	c := config.Build(r)
+
+	assert.Nil(t, c.RegisterService(context.Background(), info))
+	assert.Eventually(t, func() bool {
+		registers, _ := r.state()
+		return registers >= 3
+	}, time.Second, 5*time.Millisecond)
+
+	// heartbeats stop with the registration
+	assert.Nil(t, c.UnregisterService(context.Background(), info))
+	registers, registered := r.state()
+	assert.False(t, registered)
+	time.Sleep(30 * time.Millisecond)
+	after, _ := r.state()
+	assert.Equal(t, registers, after)
+}
+
+// blockingRegistry blocks registrations of service "slow" until release is closed
+type blockingRegistry struct {
+	*testRegistry
+	release chan struct{}
+}
+
+func (r *blockingRegistry) RegisterService(ctx context.Context, info *server.ServiceInfo) error {
+	if info.Name == "slow" {
+		<-r.release
+	}
+	return r.testRegistry.RegisterService(ctx, info)
+}
+
+func TestRegistry_RegisterUnlocked(t *testing.T) {
+	r := &blockingRegistry{testRegistry: newTestRegistry(0), release: make(chan struct{})}
+	c := testConfig().Build(r)
+
+	slow := make(chan error, 1)
+	go func() {
+		slow <- c.RegisterService(context.Background(), &server.ServiceInfo{Name: "slow", Scheme: "grpc", Address: "127.0.0.1:9091"})
+	}()
+	// a slow registration holds up no other service
+	done := make(chan error, 1)
+	go func() { done <- c.RegisterService(context.Background(), info) }()
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("registration blocked by another service")
+	}
+
+	close(r.release)
+	assert.Nil(t, <-slow)
+	assert.Nil(t, c.Close())
+}
+
+func TestRegistry_ListServices(t *testing.T) {
+	a, b := newTestRegistry(0), newTestRegistry(0)
+	other := &server.ServiceInfo{Name: "demo", Scheme: "grpc", Address: "127.0.0.1:9091"}
+	a.services = []*server.ServiceInfo{info}
+	b.services = []*server.ServiceInfo{info, other}
+
+	services, err := New(a, b).ListServices(context.Background(), "demo", "grpc")
+	assert.Nil(t, err)
+	assert.Len(t, services, 2)
+}
+
+func TestRegistry_Detach(t *testing.T) {
+	r := newTestRegistry(0)
+	c := testConfig().Build(r)
+	assert.Nil(t, c.RegisterService(context.Background(), info))
+
+	// services stay registered for the process taking them over
+	c.Detach()
+	_, registered := r.state()
+	assert.True(t, registered)
+	assert.Equal(t, ErrClosed, c.RegisterService(context.Background(), info))
+}
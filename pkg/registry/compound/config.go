@@ -0,0 +1,64 @@
+package compound
+
+import (
+	"copy/pkg/conf"
+	"copy/pkg/registry"
+	"copy/pkg/xlog"
+	"errors"
+	"time"
+)
+
+type Config struct {
+	// Timeout bounds each call to a registry
+	Timeout time.Duration `validate:"min=0s"`
+	// retry backoff of a failed registration, see xtime.Backoff
+	MinBackoff time.Duration `validate:"min=0s"`
+	MaxBackoff time.Duration `validate:"min=0s"`
+	Multiplier float64       `validate:"min=1"`
+	Jitter     float64       `validate:"min=0,max=1"`
+	// TTL re-registers every service at this interval until it is unregistered,
+	// zero registers once
+	TTL    time.Duration `validate:"min=0s"`
+	logger *xlog.Logger
+}
+
+// RawConfig ...
+func RawConfig(key string) *Config {
+	var config = DefaultConfig()
+	if err := conf.UnmarshalKey(key, config); err != nil && !conf.IsValidationError(err) && !errors.Is(err, conf.ErrInvalidKey) {
+		panic(err)
+	}
+	return config
+}
+
+// StdConfig Jupiter Standard compound registry config
+func StdConfig(name string) *Config {
+	return RawConfig("jupiter.registry." + name)
+}
+
+// DefaultConfig ...
+func DefaultConfig() *Config {
+	return &Config{
+		Timeout:    3 * time.Second,
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.2,
+		TTL:        30 * time.Second,
+		logger:     xlog.JupiterLogger,
+	}
+}
+
+// WithLogger ...
+func (config *Config) WithLogger(logger *xlog.Logger) *Config {
+	config.logger = logger
+	return config
+}
+
+// Build fans out to registries.
+func (config Config) Build(registries ...registry.Registry) *Registry {
+	if config.logger == nil {
+		config.logger = xlog.JupiterLogger
+	}
+	return newRegistry(&config, registries)
+}
@@ -0,0 +1,55 @@
+package file
+
+import (
+	"copy/pkg/conf"
+	"copy/pkg/xlog"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type Config struct {
+	// Dir is shared by the local instances, one file an instance
+	Dir string `validate:"required,writable"`
+	// Interval rescans Dir for changes missed by the watch and for stale entries
+	Interval time.Duration `validate:"min=0s"`
+	logger   *xlog.Logger
+}
+
+// RawConfig ...
+func RawConfig(key string) *Config {
+	var config = DefaultConfig()
+	if err := conf.UnmarshalKey(key, config); err != nil && !conf.IsValidationError(err) && !errors.Is(err, conf.ErrInvalidKey) {
+		panic(err)
+	}
+	return config
+}
+
+// StdConfig Jupiter Standard file registry config
+func StdConfig(name string) *Config {
+	return RawConfig("jupiter.registry." + name)
+}
+
+// DefaultConfig ...
+func DefaultConfig() *Config {
+	return &Config{
+		Dir:      filepath.Join(os.TempDir(), "jupiter-registry"),
+		Interval: 10 * time.Second,
+		logger:   xlog.JupiterLogger,
+	}
+}
+
+// WithLogger ...
+func (config *Config) WithLogger(logger *xlog.Logger) *Config {
+	config.logger = logger
+	return config
+}
+
+// Build creates Dir and watches it.
+func (config Config) Build() (*Registry, error) {
+	if config.logger == nil {
+		config.logger = xlog.JupiterLogger
+	}
+	return newRegistry(&config)
+}
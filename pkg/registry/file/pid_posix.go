@@ -0,0 +1,11 @@
+// +build !windows
+
+package file
+
+import "syscall"
+
+// processAlive reports whether process pid of this host exists
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,383 @@
+package file
+
+import (
+	"context"
+	"copy/pkg"
+	"copy/pkg/registry"
+	"copy/pkg/server"
+	"copy/pkg/xlog"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	modRegistry = "registry.file"
+	// MetadataPID and MetadataHost tell which process wrote an entry, entries of
+	// dead processes of this host are removed
+	MetadataPID  = "pid"
+	MetadataHost = "hostname"
+	ext          = ".json"
+)
+
+// ErrClosed is returned by WatchServices after Close
+var ErrClosed = errors.New("registry closed")
+
+// Registry is a registry.Registry for local development, each instance is a
+// JSON file of its ServiceInfo in a shared dir, named from its Label. Instances
+// watch the dir to discover each other, no outside service is needed.
+type Registry struct {
+	config *Config
+	dir    string
+
+	mu         sync.Mutex
+	registered map[string]string // label -> path
+	watches    map[*watch]struct{}
+
+	changed chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	wg      sync.WaitGroup
+}
+
+type watch struct {
+	name, scheme string
+	ch           chan registry.Endpoints
+	// last is the snapshot sent last, unchanged snapshots are not sent again
+	last map[string]server.ServiceInfo
+}
+
+func newRegistry(config *Config) (*Registry, error) {
+	dir, err := filepath.Abs(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Add(dir); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	r := &Registry{
+		config:     config,
+		dir:        dir,
+		registered: make(map[string]string),
+		watches:    make(map[*watch]struct{}),
+		changed:    make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	r.wg.Add(2)
+	go r.watchDir(w)
+	go r.notify()
+	return r, nil
+}
+
+// FileName returns the file name of the entry of info, its Label with the
+// characters a path cannot hold replaced
+func FileName(info *server.ServiceInfo) string {
+	name := strings.NewReplacer("://", "_", "/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", "\"", "_", "<", "_", ">", "_", "|", "_").Replace(info.Label())
+	return name + ext
+}
+
+// RegisterService writes info into the dir, with the pid and host of the process in its metadata
+func (r *Registry) RegisterService(ctx context.Context, info *server.ServiceInfo) error {
+	entry := *info
+	entry.Metadata = make(map[string]string, len(info.Metadata)+2)
+	for k, v := range info.Metadata {
+		entry.Metadata[k] = v
+	}
+	entry.Metadata[MetadataPID] = strconv.Itoa(os.Getpid())
+	entry.Metadata[MetadataHost] = pkg.HostName()
+	content, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(r.dir, FileName(info))
+	if err := writeFile(path, content); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.registered[info.Label()] = path
+	r.mu.Unlock()
+	return nil
+}
+
+// UnregisterService removes the entry of info unless another process rewrote it
+func (r *Registry) UnregisterService(ctx context.Context, info *server.ServiceInfo) error {
+	r.mu.Lock()
+	delete(r.registered, info.Label())
+	r.mu.Unlock()
+	return removeOwn(filepath.Join(r.dir, FileName(info)))
+}
+
+// ListServices lists the entries of name, of scheme unless it is empty
+func (r *Registry) ListServices(ctx context.Context, name string, scheme string) ([]*server.ServiceInfo, error) {
+	entries, err := r.scan()
+	if err != nil {
+		return nil, err
+	}
+	services := make([]*server.ServiceInfo, 0)
+	for _, info := range entries {
+		if match(info, name, scheme) {
+			services = append(services, info)
+		}
+	}
+	return services, nil
+}
+
+// WatchServices sends the entries of name as Endpoints.Nodes keyed by Label, at once
+// and on every change of them. The channel is closed when ctx is done or r is closed.
+func (r *Registry) WatchServices(ctx context.Context, name string, scheme string) (chan registry.Endpoints, error) {
+	select {
+	case <-r.done:
+		return nil, ErrClosed
+	default:
+	}
+	w := &watch{name: name, scheme: scheme, ch: make(chan registry.Endpoints, 1)}
+	entries, err := r.scan()
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.send(w, entries)
+	r.watches[w] = struct{}{}
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		select {
+		case <-ctx.Done():
+		case <-r.done:
+		}
+		r.mu.Lock()
+		delete(r.watches, w)
+		close(w.ch)
+		r.mu.Unlock()
+	}()
+	return w.ch, nil
+}
+
+// Close removes the entries registered by r and stops watching the dir, an entry
+// rewritten by another process serving the same label, such as after a hot restart,
+// is kept
+func (r *Registry) Close() error {
+	r.once.Do(func() {
+		close(r.done)
+	})
+	r.wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for label, path := range r.registered {
+		if err := removeOwn(path); err != nil {
+			r.config.logger.Warn("remove entry", xlog.FieldMod(modRegistry), xlog.FieldAddr(label), xlog.FieldErr(err))
+		}
+	}
+	r.registered = make(map[string]string)
+	return nil
+}
+
+// scan reads the entries in the dir, entries of dead processes of this host are removed
+func (r *Registry) scan() ([]*server.ServiceInfo, error) {
+	files, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*server.ServiceInfo, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ext {
+			continue
+		}
+		path := filepath.Join(r.dir, file.Name())
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			// removed since listed
+			continue
+		}
+		var info server.ServiceInfo
+		if err := json.Unmarshal(content, &info); err != nil {
+			r.config.logger.Warn("bad entry", xlog.FieldMod(modRegistry), xlog.FieldAddr(path), xlog.FieldErr(err))
+			continue
+		}
+		if stale(&info) {
+			r.config.logger.Info("remove stale entry", xlog.FieldMod(modRegistry), xlog.FieldAddr(path), xlog.String("pid", info.Metadata[MetadataPID]))
+			_ = os.Remove(path)
+			continue
+		}
+		entries = append(entries, &info)
+	}
+	return entries, nil
+}
+
+// removeOwn removes the entry of path if it was written by this process
+func removeOwn(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var info server.ServiceInfo
+	if err := json.Unmarshal(content, &info); err == nil && !ownEntry(&info) {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ownEntry reports whether info was written by this process
+func ownEntry(info *server.ServiceInfo) bool {
+	return info.Metadata[MetadataHost] == pkg.HostName() && info.Metadata[MetadataPID] == strconv.Itoa(os.Getpid())
+}
+
+// stale reports whether info was written by a dead process of this host
+func stale(info *server.ServiceInfo) bool {
+	if info.Metadata[MetadataHost] != pkg.HostName() {
+		return false
+	}
+	pid, err := strconv.Atoi(info.Metadata[MetadataPID])
+	if err != nil || pid == os.Getpid() {
+		return false
+	}
+	return !processAlive(pid)
+}
+
+func match(info *server.ServiceInfo, name, scheme string) bool {
+	return info.Name == name && (scheme == "" || info.Scheme == scheme)
+}
+
+// send sends the entries of w if they changed, a pending snapshot is replaced, r.mu is held
+func (r *Registry) send(w *watch, entries []*server.ServiceInfo) {
+	nodes := make(map[string]server.ServiceInfo)
+	for _, info := range entries {
+		if match(info, w.name, w.scheme) {
+			nodes[info.Label()] = *info
+		}
+	}
+	if w.last != nil && equalNodes(w.last, nodes) {
+		return
+	}
+	w.last = nodes
+
+	endpoints := registry.Endpoints{
+		Nodes:           nodes,
+		RouteConfigs:    make(map[string]registry.RouteConfig),
+		ConsumerConfigs: make(map[string]registry.ConsumerConfig),
+		ProviderConfigs: make(map[string]registry.ProviderConfig),
+	}
+	select {
+	case <-w.ch:
+	default:
+	}
+	w.ch <- endpoints
+}
+
+func equalNodes(a, b map[string]server.ServiceInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for label, info := range a {
+		other, ok := b[label]
+		if !ok {
+			return false
+		}
+		x, _ := json.Marshal(info)
+		y, _ := json.Marshal(other)
+		if string(x) != string(y) {
+			return false
+		}
+	}
+	return true
+}
+
+// watchDir marks a change on every event of an entry, and every Interval
+func (r *Registry) watchDir(w *fsnotify.Watcher) {
+	defer r.wg.Done()
+	defer w.Close()
+	var tick <-chan time.Time
+	if r.config.Interval > 0 {
+		ticker := time.NewTicker(r.config.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case event := <-w.Events:
+			if filepath.Ext(event.Name) != ext {
+				continue
+			}
+		case err := <-w.Errors:
+			r.config.logger.Error("watch dir", xlog.FieldMod(modRegistry), xlog.FieldAddr(r.dir), xlog.FieldErr(err))
+			continue
+		case <-tick:
+		case <-r.done:
+			return
+		}
+		select {
+		case r.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// notify rescans the dir on change and updates the watches
+func (r *Registry) notify() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.changed:
+		case <-r.done:
+			return
+		}
+		entries, err := r.scan()
+		if err != nil {
+			r.config.logger.Error("scan dir", xlog.FieldMod(modRegistry), xlog.FieldAddr(r.dir), xlog.FieldErr(err))
+			continue
+		}
+		r.mu.Lock()
+		for w := range r.watches {
+			r.send(w, entries)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// writeFile replaces path by a renamed temp file, readers never see a partial entry
+func writeFile(path string, content []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".entry")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package file
+
+import (
+	"context"
+	"copy/pkg"
+	"copy/pkg/registry"
+	"copy/pkg/server"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRegistry(t *testing.T, dir string) *Registry {
+	config := DefaultConfig()
+	config.Dir = dir
+	r, err := config.Build()
+	assert.Nil(t, err)
+	return r
+}
+
+func next(t *testing.T, ch chan registry.Endpoints) registry.Endpoints {
+	select {
+	case endpoints := <-ch:
+		return endpoints
+	case <-time.After(5 * time.Second):
+		t.Fatal("endpoints not sent")
+		return registry.Endpoints{}
+	}
+}
+
+func TestRegistry_Discovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-registry")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	provider, consumer := newTestRegistry(t, dir), newTestRegistry(t, dir)
+	defer consumer.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watch, err := consumer.WatchServices(ctx, "demo", "grpc")
+	assert.Nil(t, err)
+	assert.Len(t, next(t, watch).Nodes, 0)
+
+	info := &server.ServiceInfo{Name: "demo", Scheme: "grpc", Address: "127.0.0.1:9090"}
+	assert.Nil(t, provider.RegisterService(ctx, info))
+	assert.FileExists(t, filepath.Join(dir, "grpc_127.0.0.1_9090.json"))
+	nodes := next(t, watch).Nodes
+	assert.Equal(t, "127.0.0.1:9090", nodes["grpc://127.0.0.1:9090"].Address)
+	assert.Equal(t, strconv.Itoa(os.Getpid()), nodes["grpc://127.0.0.1:9090"].Metadata[MetadataPID])
+
+	services, err := consumer.ListServices(ctx, "demo", "")
+	assert.Nil(t, err)
+	assert.Len(t, services, 1)
+	services, err = consumer.ListServices(ctx, "other", "")
+	assert.Nil(t, err)
+	assert.Len(t, services, 0)
+
+	// entries are removed on close
+	assert.Nil(t, provider.Close())
+	assert.Len(t, next(t, watch).Nodes, 0)
+
+	cancel()
+	for range watch {
+	}
+}
+
+func TestRegistry_Stale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-registry")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// an entry left by a dead process
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	assert.Nil(t, cmd.Run())
+	info := server.ServiceInfo{Name: "demo", Scheme: "grpc", Address: "127.0.0.1:9091", Metadata: map[string]string{
+		MetadataPID:  strconv.Itoa(cmd.Process.Pid),
+		MetadataHost: pkg.HostName(),
+	}}
+	content, _ := json.Marshal(info)
+	path := filepath.Join(dir, FileName(&info))
+	assert.Nil(t, ioutil.WriteFile(path, content, 0644))
+
+	r := newTestRegistry(t, dir)
+	defer r.Close()
+	services, err := r.ListServices(context.Background(), "demo", "grpc")
+	assert.Nil(t, err)
+	assert.Len(t, services, 0)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRegistry_CloseKeepsOthers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-registry")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	r := newTestRegistry(t, dir)
+	info := &server.ServiceInfo{Name: "demo", Scheme: "grpc", Address: "127.0.0.1:9092"}
+	assert.Nil(t, r.RegisterService(context.Background(), info))
+
+	// the entry is rewritten by a live process serving the same label
+	other := *info
+	other.Metadata = map[string]string{MetadataPID: strconv.Itoa(os.Getppid()), MetadataHost: pkg.HostName()}
+	content, _ := json.Marshal(other)
+	path := filepath.Join(dir, FileName(info))
+	assert.Nil(t, ioutil.WriteFile(path, content, 0644))
+
+	assert.Nil(t, r.UnregisterService(context.Background(), info))
+	assert.Nil(t, r.Close())
+	assert.FileExists(t, path)
+}
@@ -0,0 +1,378 @@
+package memory
+
+import (
+	"context"
+	"copy/constant"
+	"copy/pkg/registry"
+	"copy/pkg/server"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned after Close
+var ErrClosed = errors.New("registry closed")
+
+// EventType is what happened to a service.
+type EventType int
+
+const (
+	// EventAdd is a service registered
+	EventAdd EventType = iota + 1
+	// EventUpdate is a registered service registered again with changes
+	EventUpdate
+	// EventDelete is a service unregistered
+	EventDelete
+)
+
+// String ...
+func (t EventType) String() string {
+	switch t {
+	case EventAdd:
+		return "add"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of a service delivered by Watch.
+type Event struct {
+	Type    EventType
+	Service server.ServiceInfo
+}
+
+// Registry is an in-memory registry.Registry for tests. Besides the Registry
+// interface it lists services by kind and streams add, update and delete events,
+// and faults such as failed registrations, delayed events and dropped watches
+// can be injected.
+type Registry struct {
+	mu       sync.Mutex
+	services map[string]server.ServiceInfo
+	watchers map[*watcher]struct{}
+	closed   bool
+
+	// injected faults
+	failures   int
+	failErr    error
+	eventDelay time.Duration
+}
+
+// New creates an empty registry.
+func New() *Registry {
+	return &Registry{
+		services: make(map[string]server.ServiceInfo),
+		watchers: make(map[*watcher]struct{}),
+	}
+}
+
+// RegisterService adds info, or updates the service of the same label
+func (r *Registry) RegisterService(ctx context.Context, info *server.ServiceInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	if r.failures != 0 {
+		if r.failures > 0 {
+			r.failures--
+		}
+		return r.failErr
+	}
+	label := info.Label()
+	event := Event{Type: EventAdd, Service: copyInfo(info)}
+	if old, ok := r.services[label]; ok {
+		if equalInfo(old, event.Service) {
+			return nil
+		}
+		event.Type = EventUpdate
+	}
+	r.services[label] = event.Service
+	r.publish(event)
+	return nil
+}
+
+// UnregisterService deletes the service of the label of info
+func (r *Registry) UnregisterService(ctx context.Context, info *server.ServiceInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	old, ok := r.services[info.Label()]
+	if !ok {
+		return nil
+	}
+	delete(r.services, info.Label())
+	r.publish(Event{Type: EventDelete, Service: old})
+	return nil
+}
+
+// ListServices lists the services of name, of scheme unless it is empty
+func (r *Registry) ListServices(ctx context.Context, name string, scheme string) ([]*server.ServiceInfo, error) {
+	return r.list(func(info *server.ServiceInfo) bool {
+		return info.Name == name && (scheme == "" || info.Scheme == scheme)
+	}), nil
+}
+
+// List lists the services of name and kind, sorted by label
+func (r *Registry) List(name string, kind constant.ServiceKind) []*server.ServiceInfo {
+	return r.list(func(info *server.ServiceInfo) bool {
+		return info.Name == name && info.Kind == kind
+	})
+}
+
+// Watch streams the events of the services of name, the registered ones are
+// delivered as EventAdd first. The stream is closed when ctx is done, the
+// registry is closed or the watch is dropped by DropWatches.
+func (r *Registry) Watch(ctx context.Context, name string) (<-chan Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, ErrClosed
+	}
+	w := newWatcher(r, name)
+	for _, info := range r.sorted(func(info *server.ServiceInfo) bool { return info.Name == name }) {
+		w.push(Event{Type: EventAdd, Service: *info})
+	}
+	r.watchers[w] = struct{}{}
+	go w.run(ctx)
+	return w.out, nil
+}
+
+// WatchServices sends the services of name and scheme as Endpoints.Nodes keyed by
+// label, at once and on every event, see Watch.
+func (r *Registry) WatchServices(ctx context.Context, name string, scheme string) (chan registry.Endpoints, error) {
+	events, err := r.Watch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan registry.Endpoints)
+	go func() {
+		defer close(ch)
+		nodes := make(map[string]server.ServiceInfo)
+		send := func() bool {
+			endpoints := registry.Endpoints{
+				Nodes:           make(map[string]server.ServiceInfo, len(nodes)),
+				RouteConfigs:    make(map[string]registry.RouteConfig),
+				ConsumerConfigs: make(map[string]registry.ConsumerConfig),
+				ProviderConfigs: make(map[string]registry.ProviderConfig),
+			}
+			for label, info := range nodes {
+				endpoints.Nodes[label] = info
+			}
+			select {
+			case ch <- endpoints:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if !send() {
+			return
+		}
+		for event := range events {
+			if scheme != "" && event.Service.Scheme != scheme {
+				continue
+			}
+			if event.Type == EventDelete {
+				delete(nodes, event.Service.Label())
+			} else {
+				nodes[event.Service.Label()] = event.Service
+			}
+			if !send() {
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Close drops every watch, later calls fail with ErrClosed
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	r.dropWatches()
+	return nil
+}
+
+// FailRegistrations fails the next n registrations with err, n < 0 fails all until
+// FailRegistrations(0, nil)
+func (r *Registry) FailRegistrations(n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures, r.failErr = n, err
+}
+
+// SetEventDelay delays the delivery of every later event by d, the order of events is kept
+func (r *Registry) SetEventDelay(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.eventDelay = d
+}
+
+// DropWatches closes every watch stream, as a lost connection to a registry does.
+// Pending events are lost.
+func (r *Registry) DropWatches() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dropWatches()
+}
+
+func (r *Registry) dropWatches() {
+	for w := range r.watchers {
+		w.drop()
+		delete(r.watchers, w)
+	}
+}
+
+// publish queues event to the watchers of its name, r.mu is held
+func (r *Registry) publish(event Event) {
+	for w := range r.watchers {
+		if w.name == event.Service.Name {
+			w.push(event)
+		}
+	}
+}
+
+func (r *Registry) list(filter func(*server.ServiceInfo) bool) []*server.ServiceInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sorted(filter)
+}
+
+func (r *Registry) sorted(filter func(*server.ServiceInfo) bool) []*server.ServiceInfo {
+	services := make([]*server.ServiceInfo, 0)
+	for _, info := range r.services {
+		info := copyInfo(&info)
+		if filter(&info) {
+			services = append(services, &info)
+		}
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Label() < services[j].Label()
+	})
+	return services
+}
+
+func (r *Registry) delay() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.eventDelay
+}
+
+func (r *Registry) removeWatcher(w *watcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.watchers, w)
+}
+
+// watcher queues events without blocking the registry and delivers them in order
+type watcher struct {
+	registry *Registry
+	name     string
+	out      chan Event
+
+	mu      sync.Mutex
+	queue   []Event
+	pending chan struct{}
+	dropped chan struct{}
+	once    sync.Once
+}
+
+func newWatcher(r *Registry, name string) *watcher {
+	return &watcher{
+		registry: r,
+		name:     name,
+		out:      make(chan Event),
+		pending:  make(chan struct{}, 1),
+		dropped:  make(chan struct{}),
+	}
+}
+
+func (w *watcher) push(event Event) {
+	w.mu.Lock()
+	w.queue = append(w.queue, event)
+	w.mu.Unlock()
+	select {
+	case w.pending <- struct{}{}:
+	default:
+	}
+}
+
+func (w *watcher) pop() (Event, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.queue) == 0 {
+		return Event{}, false
+	}
+	event := w.queue[0]
+	w.queue = w.queue[1:]
+	return event, true
+}
+
+func (w *watcher) drop() {
+	w.once.Do(func() {
+		close(w.dropped)
+	})
+}
+
+func (w *watcher) run(ctx context.Context) {
+	defer close(w.out)
+	defer w.registry.removeWatcher(w)
+	for {
+		event, ok := w.pop()
+		if !ok {
+			select {
+			case <-w.pending:
+				continue
+			case <-ctx.Done():
+				return
+			case <-w.dropped:
+				return
+			}
+		}
+		if d := w.registry.delay(); d > 0 {
+			timer := time.NewTimer(d)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-w.dropped:
+				timer.Stop()
+				return
+			}
+		}
+		select {
+		case w.out <- event:
+		case <-ctx.Done():
+			return
+		case <-w.dropped:
+			return
+		}
+	}
+}
+
+func copyInfo(info *server.ServiceInfo) server.ServiceInfo {
+	copied := *info
+	if info.Metadata != nil {
+		copied.Metadata = make(map[string]string, len(info.Metadata))
+		for k, v := range info.Metadata {
+			copied.Metadata[k] = v
+		}
+	}
+	return copied
+}
+
+func equalInfo(a, b server.ServiceInfo) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"context"
+	"copy/constant"
+	"copy/pkg/registry"
+	"copy/pkg/server"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the registry is used by the framework through the interface
+var _ registry.Registry = (*Registry)(nil)
+
+func testInfo(address string, kind constant.ServiceKind) *server.ServiceInfo {
+	return &server.ServiceInfo{Name: "demo", Scheme: "grpc", Address: address, Kind: kind, Enable: true}
+}
+
+func next(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("watch closed")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+	return Event{}
+}
+
+func TestRegistry_List(t *testing.T) {
+	r := New()
+	ctx := context.Background()
+	assert.Nil(t, r.RegisterService(ctx, testInfo("127.0.0.1:9091", constant.ServiceProvider)))
+	assert.Nil(t, r.RegisterService(ctx, testInfo("127.0.0.1:9090", constant.ServiceProvider)))
+	assert.Nil(t, r.RegisterService(ctx, testInfo("127.0.0.1:9999", constant.ServiceGovernor)))
+
+	providers := r.List("demo", constant.ServiceProvider)
+	if assert.Len(t, providers, 2) {
+		assert.Equal(t, "127.0.0.1:9090", providers[0].Address)
+		assert.Equal(t, "127.0.0.1:9091", providers[1].Address)
+	}
+	assert.Len(t, r.List("demo", constant.ServiceGovernor), 1)
+	assert.Empty(t, r.List("other", constant.ServiceProvider))
+
+	services, err := r.ListServices(ctx, "demo", "grpc")
+	assert.Nil(t, err)
+	assert.Len(t, services, 3)
+
+	assert.Nil(t, r.UnregisterService(ctx, testInfo("127.0.0.1:9090", constant.ServiceProvider)))
+	assert.Len(t, r.List("demo", constant.ServiceProvider), 1)
+}
+
+func TestRegistry_Watch(t *testing.T) {
+	r := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	info := testInfo("127.0.0.1:9090", constant.ServiceProvider)
+	assert.Nil(t, r.RegisterService(ctx, info))
+
+	events, err := r.Watch(ctx, "demo")
+	assert.Nil(t, err)
+	event := next(t, events)
+	assert.Equal(t, EventAdd, event.Type)
+	assert.Equal(t, info.Label(), event.Service.Label())
+
+	// registering again unchanged is not an event
+	assert.Nil(t, r.RegisterService(ctx, info))
+	info.Weight = 50
+	assert.Nil(t, r.RegisterService(ctx, info))
+	event = next(t, events)
+	assert.Equal(t, EventUpdate, event.Type)
+	assert.Equal(t, float64(50), event.Service.Weight)
+
+	assert.Nil(t, r.UnregisterService(ctx, info))
+	assert.Equal(t, EventDelete, next(t, events).Type)
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestRegistry_WatchServices(t *testing.T) {
+	r := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := r.WatchServices(ctx, "demo", "grpc")
+	assert.Nil(t, err)
+	assert.Empty(t, (<-ch).Nodes)
+
+	info := testInfo("127.0.0.1:9090", constant.ServiceProvider)
+	assert.Nil(t, r.RegisterService(ctx, info))
+	endpoints := <-ch
+	assert.Contains(t, endpoints.Nodes, info.Label())
+
+	assert.Nil(t, r.UnregisterService(ctx, info))
+	assert.Empty(t, (<-ch).Nodes)
+}
+
+func TestRegistry_FailRegistrations(t *testing.T) {
+	r := New()
+	ctx := context.Background()
+	info := testInfo("127.0.0.1:9090", constant.ServiceProvider)
+	unavailable := errors.New("unavailable")
+
+	r.FailRegistrations(2, unavailable)
+	assert.Equal(t, unavailable, r.RegisterService(ctx, info))
+	assert.Equal(t, unavailable, r.RegisterService(ctx, info))
+	assert.Nil(t, r.RegisterService(ctx, info))
+	assert.Len(t, r.List("demo", constant.ServiceProvider), 1)
+
+	r.FailRegistrations(-1, unavailable)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, unavailable, r.RegisterService(ctx, testInfo("127.0.0.1:9091", constant.ServiceProvider)))
+	}
+	r.FailRegistrations(0, nil)
+	assert.Nil(t, r.RegisterService(ctx, testInfo("127.0.0.1:9091", constant.ServiceProvider)))
+}
+
+func TestRegistry_DelayEvents(t *testing.T) {
+	r := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := r.Watch(ctx, "demo")
+	assert.Nil(t, err)
+
+	r.SetEventDelay(50 * time.Millisecond)
+	start := time.Now()
+	assert.Nil(t, r.RegisterService(ctx, testInfo("127.0.0.1:9090", constant.ServiceProvider)))
+	assert.Nil(t, r.RegisterService(ctx, testInfo("127.0.0.1:9091", constant.ServiceProvider)))
+	// registering does not wait for the delayed delivery
+	assert.True(t, time.Since(start) < 50*time.Millisecond)
+
+	assert.Equal(t, "127.0.0.1:9090", next(t, events).Service.Address)
+	assert.Equal(t, "127.0.0.1:9091", next(t, events).Service.Address)
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+}
+
+func TestRegistry_DropWatches(t *testing.T) {
+	r := New()
+	ctx := context.Background()
+	events, err := r.Watch(ctx, "demo")
+	assert.Nil(t, err)
+	ch, err := r.WatchServices(ctx, "demo", "")
+	assert.Nil(t, err)
+	<-ch
+
+	r.DropWatches()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("watch not dropped")
+	}
+	for range ch {
+	}
+
+	// watching again recovers the registered services
+	assert.Nil(t, r.RegisterService(ctx, testInfo("127.0.0.1:9090", constant.ServiceProvider)))
+	events, err = r.Watch(ctx, "demo")
+	assert.Nil(t, err)
+	assert.Equal(t, EventAdd, next(t, events).Type)
+
+	assert.Nil(t, r.Close())
+	for range events {
+	}
+	_, err = r.Watch(ctx, "demo")
+	assert.Equal(t, ErrClosed, err)
+}
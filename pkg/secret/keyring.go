@@ -0,0 +1,148 @@
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// KeySize is the size of an AES-256 key
+const KeySize = 32
+
+// ErrKeyNotFound is returned by Keyring.Key of an unknown key id
+var ErrKeyNotFound = errors.New("secret key not found")
+
+// Keyring provides the keys to encrypt and decrypt secrets.
+type Keyring interface {
+	// Key returns the key of id
+	Key(id string) ([]byte, error)
+	// Primary returns the key new secrets are encrypted with
+	Primary() (id string, key []byte, err error)
+}
+
+// Key is a named key of a keyring.
+type Key struct {
+	ID  string
+	Key []byte
+}
+
+// NewKey generates a random key of id
+func NewKey(id string) (Key, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return Key{}, err
+	}
+	return Key{ID: id, Key: key}, nil
+}
+
+// String formats k as a line of key file, id:base64(key)
+func (k Key) String() string {
+	return k.ID + ":" + base64.StdEncoding.EncodeToString(k.Key)
+}
+
+// ParseKey parses a key formatted by Key.String
+func ParseKey(s string) (Key, error) {
+	kv := strings.SplitN(strings.TrimSpace(s), ":", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return Key{}, errors.New("invalid secret key, want id:base64")
+	}
+	key, err := base64.StdEncoding.DecodeString(kv[1])
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid secret key %s: %w", kv[0], err)
+	}
+	if len(key) != KeySize {
+		return Key{}, fmt.Errorf("invalid secret key %s: size %d, want %d", kv[0], len(key), KeySize)
+	}
+	return Key{ID: kv[0], Key: key}, nil
+}
+
+// Keys is a Keyring of a list of keys, the last one is the primary.
+type Keys []Key
+
+// Key ...
+func (ks Keys) Key(id string) ([]byte, error) {
+	for _, k := range ks {
+		if k.ID == id {
+			return k.Key, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", id, ErrKeyNotFound)
+}
+
+// Primary ...
+func (ks Keys) Primary() (string, []byte, error) {
+	if len(ks) == 0 {
+		return "", nil, ErrKeyNotFound
+	}
+	k := ks[len(ks)-1]
+	return k.ID, k.Key, nil
+}
+
+// FileKeyring reads the keys from a key file, one key formatted by Key.String a line,
+// lines starting with # are comments. The last key is the primary, a rotation appends a new key.
+func FileKeyring(path string) (Keys, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys Keys
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, err := ParseKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("key file %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, scanner.Err()
+}
+
+// EnvKeyring reads the keys from the environment variable name, keys formatted
+// by Key.String are separated by comma. The last key is the primary.
+func EnvKeyring(name string) (Keys, error) {
+	var keys Keys
+	for _, s := range strings.Split(os.Getenv(name), ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		key, err := ParseKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", name, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Chain is a Keyring looking up keys in order, the primary is of the first keyring having one.
+type Chain []Keyring
+
+// Key ...
+func (c Chain) Key(id string) ([]byte, error) {
+	for _, kr := range c {
+		if key, err := kr.Key(id); err == nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", id, ErrKeyNotFound)
+}
+
+// Primary ...
+func (c Chain) Primary() (string, []byte, error) {
+	for _, kr := range c {
+		if id, key, err := kr.Primary(); err == nil {
+			return id, key, nil
+		}
+	}
+	return "", nil, ErrKeyNotFound
+}
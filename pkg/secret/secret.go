@@ -0,0 +1,117 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	prefix = "ENC("
+	suffix = ")"
+)
+
+// Mask replaces a secret in dumps
+const Mask = "******"
+
+// ErrInvalidSecret is returned by Decrypt of a malformed value
+var ErrInvalidSecret = errors.New("invalid secret")
+
+// IsEncrypted reports whether value is written as ENC(...)
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix) && strings.HasSuffix(value, suffix)
+}
+
+// Encrypt seals plaintext by AES-GCM with the primary key of kr, returns ENC(<key id>:<base64(nonce|ciphertext)>)
+func Encrypt(kr Keyring, plaintext string) (string, error) {
+	id, key, err := kr.Primary()
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(id))
+	return prefix + id + ":" + base64.StdEncoding.EncodeToString(sealed) + suffix, nil
+}
+
+// Decrypt opens a value returned by Encrypt with the key of its id in kr
+func Decrypt(kr Keyring, value string) (string, error) {
+	id, sealed, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	key, err := kr.Key(id)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", ErrInvalidSecret
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("decrypt with key %s: %w", id, err)
+	}
+	return string(plaintext), nil
+}
+
+// Rotate encrypts value again with the primary key of kr, a value of the primary key is kept
+func Rotate(kr Keyring, value string) (string, error) {
+	id, _, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	primary, _, err := kr.Primary()
+	if err != nil {
+		return "", err
+	}
+	if id == primary {
+		return value, nil
+	}
+	plaintext, err := Decrypt(kr, value)
+	if err != nil {
+		return "", err
+	}
+	return Encrypt(kr, plaintext)
+}
+
+// KeyID returns the id of the key value is encrypted with
+func KeyID(value string) (string, error) {
+	id, _, err := parse(value)
+	return id, err
+}
+
+func parse(value string) (id string, sealed []byte, err error) {
+	if !IsEncrypted(value) {
+		return "", nil, ErrInvalidSecret
+	}
+	kv := strings.SplitN(strings.TrimSuffix(strings.TrimPrefix(value, prefix), suffix), ":", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return "", nil, ErrInvalidSecret
+	}
+	if sealed, err = base64.StdEncoding.DecodeString(kv[1]); err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidSecret, err)
+	}
+	return kv[0], sealed, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,86 @@
+package secret
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	old, err := NewKey("old")
+	assert.Nil(t, err)
+	kr := Keys{old}
+
+	value, err := Encrypt(kr, "p@ssw0rd")
+	assert.Nil(t, err)
+	assert.True(t, IsEncrypted(value))
+	assert.True(t, strings.HasPrefix(value, "ENC(old:"))
+	plaintext, err := Decrypt(kr, value)
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ssw0rd", plaintext)
+
+	// a value sealed with the same key under another id is rejected
+	_, err = Decrypt(Keys{{ID: "other", Key: old.Key}}, strings.Replace(value, "old:", "other:", 1))
+	assert.Error(t, err)
+	_, err = Decrypt(Keys{}, value)
+	assert.True(t, errors.Is(err, ErrKeyNotFound))
+	_, err = Decrypt(kr, "ENC(old)")
+	assert.True(t, errors.Is(err, ErrInvalidSecret))
+
+	// rotation encrypts with the new primary key, the old key still decrypts old values
+	primary, err := NewKey("new")
+	assert.Nil(t, err)
+	kr = Keys{old, primary}
+	rotated, err := Rotate(kr, value)
+	assert.Nil(t, err)
+	id, err := KeyID(rotated)
+	assert.Nil(t, err)
+	assert.Equal(t, "new", id)
+	plaintext, err = Decrypt(kr, rotated)
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ssw0rd", plaintext)
+	again, err := Rotate(kr, rotated)
+	assert.Nil(t, err)
+	assert.Equal(t, rotated, again)
+}
+
+func TestKeyring(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	k1, _ := NewKey("k1")
+	k2, _ := NewKey("k2")
+	path := filepath.Join(dir, "secret.keys")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("# keys\n"+k1.String()+"\n\n"+k2.String()+"\n"), 0600))
+	fileKeys, err := FileKeyring(path)
+	assert.Nil(t, err)
+	assert.Equal(t, Keys{k1, k2}, fileKeys)
+
+	k3, _ := NewKey("k3")
+	os.Setenv("TEST_SECRET_KEYS", k3.String())
+	defer os.Unsetenv("TEST_SECRET_KEYS")
+	envKeys, err := EnvKeyring("TEST_SECRET_KEYS")
+	assert.Nil(t, err)
+
+	kr := Chain{fileKeys, envKeys}
+	id, _, err := kr.Primary()
+	assert.Nil(t, err)
+	assert.Equal(t, "k2", id)
+	key, err := kr.Key("k3")
+	assert.Nil(t, err)
+	assert.Equal(t, k3.Key, key)
+	_, err = kr.Key("k4")
+	assert.True(t, errors.Is(err, ErrKeyNotFound))
+
+	_, err = ParseKey("k5:" + strings.Repeat("A", 8))
+	assert.Error(t, err)
+	assert.Nil(t, ioutil.WriteFile(path, []byte("broken\n"), 0600))
+	_, err = FileKeyring(path)
+	assert.Error(t, err)
+}
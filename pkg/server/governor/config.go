@@ -0,0 +1,70 @@
+package governor
+
+import (
+	"copy/pkg/conf"
+	"copy/pkg/xlog"
+	"errors"
+	"fmt"
+)
+
+// ModName ..
+const ModName = "govern"
+
+// Config ...
+type Config struct {
+	// Host is loopback by default, the routes posting loggers and servers have no auth
+	Host string
+	// Port 0, the default, listens on a free port which differs on every start, the
+	// address is told by Info and by the warning of the app starting the governor.
+	// Set a port for a fixed address, such as for a metrics scraper.
+	Port    int    `validate:"min=0,max=65535"`
+	Network string `json:"network" toml:"network" validate:"oneof=tcp tcp4 tcp6"`
+	Enable  bool
+	logger  *xlog.Logger
+}
+
+// StdConfig represents Standard governor server config
+// which will parse config by conf package
+func StdConfig(name string) *Config {
+	return RawConfig("jupiter.server." + name)
+}
+
+// RawConfig ...
+func RawConfig(key string) *Config {
+	var config = DefaultConfig()
+	if err := conf.UnmarshalKey(key, config); err != nil && !conf.IsValidationError(err) && !errors.Is(err, conf.ErrInvalidKey) {
+		panic(err)
+	}
+	return config
+}
+
+// DefaultConfig represents default config
+// User should construct config base on DefaultConfig.
+// The governor listens on a free port of loopback by default, see Config.Port.
+func DefaultConfig() *Config {
+	return &Config{
+		Enable:  true,
+		Host:    "127.0.0.1",
+		Network: "tcp4",
+		logger:  xlog.JupiterLogger,
+	}
+}
+
+// WithLogger ...
+func (config *Config) WithLogger(logger *xlog.Logger) *Config {
+	config.logger = logger
+	return config
+}
+
+// Build listens on Address.
+func (config Config) Build() (*Server, error) {
+	if config.logger == nil {
+		config.logger = xlog.JupiterLogger
+	}
+	return newServer(&config)
+}
+
+// Address ...
+func (config Config) Address() string {
+	return fmt.Sprintf("%s:%d", config.Host, config.Port)
+}
@@ -0,0 +1,151 @@
+package governor
+
+import (
+	"copy/pkg"
+	"copy/pkg/conf"
+	"copy/pkg/secret"
+	"copy/pkg/xlog"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultRoutes are served by every governor server
+var defaultRoutes = &routeTable{}
+
+type routeTable struct {
+	mu       sync.Mutex
+	patterns []string
+	handlers map[string]http.Handler
+}
+
+func (t *routeTable) add(pattern string, handler http.Handler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.handlers == nil {
+		t.handlers = make(map[string]http.Handler)
+	}
+	if _, ok := t.handlers[pattern]; !ok {
+		t.patterns = append(t.patterns, pattern)
+	}
+	t.handlers[pattern] = handler
+}
+
+func (t *routeTable) each(fn func(pattern string, handler http.Handler)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, pattern := range t.patterns {
+		fn(pattern, t.handlers[pattern])
+	}
+}
+
+// secretEnvs are the words of the names of environment variables masked by /env
+var secretEnvs = []string{"SECRET", "PASSWORD", "PASSWD", "TOKEN", "CREDENTIAL", "PRIVATE"}
+
+func init() {
+	HandleFunc("/debug/pprof/", pprof.Index)
+	HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	HandleFunc("/debug/pprof/profile", pprof.Profile)
+	HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// secrets are masked by conf.Traverse
+	HandleFunc("/configs", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, r, conf.Traverse("."))
+	})
+
+	HandleFunc("/env", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, r, environ())
+	})
+
+	HandleFunc("/loggers", handleLoggers)
+
+	HandleFunc("/build", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, r, map[string]string{
+			"name":           pkg.Name(),
+			"appID":          pkg.AppID(),
+			"appMode":        pkg.AppMode(),
+			"appVersion":     pkg.AppVersion(),
+			"jupiterVersion": pkg.JupiterVersion(),
+			"buildUser":      pkg.BuildUser(),
+			"buildHost":      pkg.BuildHost(),
+			"buildTime":      pkg.BuildTime(),
+			"startTime":      pkg.StartTime(),
+			"hostName":       pkg.HostName(),
+			"goVersion":      pkg.GoVersion(),
+		})
+	})
+}
+
+// HandleFunc adds a route to every governor server built later
+func HandleFunc(pattern string, handler http.HandlerFunc) {
+	defaultRoutes.add(pattern, handler)
+}
+
+// handleLoggers lists the named loggers and their levels, POST name, level and an
+// optional ttl such as 10m changes a level, the operator is told by X-Operator.
+func handleLoggers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		change := xlog.LevelChange{
+			Name:     r.FormValue("name"),
+			Level:    r.FormValue("level"),
+			Operator: r.Header.Get("X-Operator"),
+		}
+		if change.Operator == "" {
+			change.Operator = r.RemoteAddr
+		}
+		if ttl := r.FormValue("ttl"); ttl != "" {
+			d, err := time.ParseDuration(ttl)
+			if err != nil {
+				http.Error(w, "invalid ttl: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			change.TTL = d
+		}
+		if _, ok := xlog.Lookup(change.Name); !ok {
+			http.Error(w, "logger not found: "+change.Name, http.StatusNotFound)
+			return
+		}
+		if err := xlog.ChangeLevel(change); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	WriteJSON(w, r, xlog.Loggers())
+}
+
+// environ returns the environment with the values of secret variables masked
+func environ() []string {
+	envs := os.Environ()
+	for i, env := range envs {
+		kv := strings.SplitN(env, "=", 2)
+		name := strings.ToUpper(kv[0])
+		for _, word := range secretEnvs {
+			if strings.Contains(name, word) {
+				envs[i] = kv[0] + "=" + secret.Mask
+				break
+			}
+		}
+	}
+	return envs
+}
+
+// WriteJSON writes v as JSON, indented with ?pretty=true
+func WriteJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	encoder := json.NewEncoder(w)
+	if r.URL.Query().Get("pretty") == "true" {
+		encoder.SetIndent("", "    ")
+	}
+	_ = encoder.Encode(v)
+}
@@ -0,0 +1,99 @@
+package governor
+
+import (
+	"context"
+	"copy/constant"
+	"copy/pkg/server"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Server is the admin http server of an application, it serves the routes of
+// HandleFunc and those added to the server itself.
+type Server struct {
+	*http.Server
+	listener net.Listener
+	mux      *http.ServeMux
+	info     *server.ServiceInfo
+	*Config
+
+	mu     sync.Mutex
+	routes []string
+}
+
+func newServer(config *Config) (*Server, error) {
+	listener, err := net.Listen(config.Network, config.Address())
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener: listener,
+		mux:      http.NewServeMux(),
+		Config:   config,
+	}
+	s.Server = &http.Server{
+		Addr:    listener.Addr().String(),
+		Handler: s.mux,
+	}
+	info := server.ApplyOptions(
+		server.WithScheme("http"),
+		server.WithAddress(listener.Addr().String()),
+		server.WithKind(constant.ServiceGovernor),
+	)
+	s.info = &info
+	s.HandleFunc("/routes", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, r, s.Routes())
+	})
+	defaultRoutes.each(func(pattern string, handler http.Handler) {
+		s.Handle(pattern, handler)
+	})
+	return s, nil
+}
+
+// Handle adds a route to s
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mux.Handle(pattern, handler)
+	s.routes = append(s.routes, pattern)
+}
+
+// HandleFunc adds a route to s
+func (s *Server) HandleFunc(pattern string, handler http.HandlerFunc) {
+	s.Handle(pattern, handler)
+}
+
+// Routes returns the sorted routes of s
+func (s *Server) Routes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	routes := append([]string(nil), s.routes...)
+	sort.Strings(routes)
+	return routes
+}
+
+// Serve ..
+func (s *Server) Serve() error {
+	err := s.Server.Serve(s.listener)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Stop ..
+func (s *Server) Stop() error {
+	return s.Server.Close()
+}
+
+// GracefulStop ..
+func (s *Server) GracefulStop(ctx context.Context) error {
+	return s.Server.Shutdown(ctx)
+}
+
+// Info ..
+func (s *Server) Info() *server.ServiceInfo {
+	return s.info
+}
@@ -0,0 +1,108 @@
+package governor
+
+import (
+	"context"
+	"copy/constant"
+	"copy/pkg/secret"
+	"copy/pkg/xlog"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func testServer(t *testing.T) *Server {
+	config := DefaultConfig()
+	config.Host = "127.0.0.1"
+	s, err := config.Build()
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, s.Serve())
+	}()
+	t.Cleanup(func() {
+		http.DefaultClient.CloseIdleConnections()
+		assert.Nil(t, s.GracefulStop(context.Background()))
+	})
+	return s
+}
+
+func get(t *testing.T, s *Server, path string, v interface{}) int {
+	resp, err := http.Get("http://" + s.Info().Address + path)
+	if !assert.Nil(t, err) {
+		return 0
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+func TestServer(t *testing.T) {
+	s := testServer(t)
+	s.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, r, "hello")
+	})
+	info := s.Info()
+	assert.Equal(t, constant.ServiceGovernor, info.Kind)
+	assert.Equal(t, "http", info.Scheme)
+	assert.NotEqual(t, "127.0.0.1:0", info.Address)
+
+	var hello string
+	assert.Equal(t, http.StatusOK, get(t, s, "/hello", &hello))
+	assert.Equal(t, "hello", hello)
+
+	var routes []string
+	assert.Equal(t, http.StatusOK, get(t, s, "/routes", &routes))
+	assert.Subset(t, routes, []string{"/build", "/configs", "/debug/pprof/", "/env", "/hello", "/routes"})
+
+	var build map[string]string
+	assert.Equal(t, http.StatusOK, get(t, s, "/build", &build))
+	assert.Contains(t, build, "goVersion")
+	assert.Equal(t, http.StatusOK, get(t, s, "/debug/pprof/cmdline", nil))
+}
+
+func TestServer_Env(t *testing.T) {
+	os.Setenv("GOVERNOR_TEST_TOKEN", "plaintext")
+	os.Setenv("GOVERNOR_TEST_MODE", "dev")
+	defer os.Unsetenv("GOVERNOR_TEST_TOKEN")
+	defer os.Unsetenv("GOVERNOR_TEST_MODE")
+	s := testServer(t)
+
+	var envs []string
+	assert.Equal(t, http.StatusOK, get(t, s, "/env", &envs))
+	assert.Contains(t, envs, "GOVERNOR_TEST_TOKEN="+secret.Mask)
+	assert.Contains(t, envs, "GOVERNOR_TEST_MODE=dev")
+}
+
+func TestServer_Loggers(t *testing.T) {
+	s := testServer(t)
+	logger := xlog.Config{Name: "governor.log", Level: "info", Core: zapcore.NewNopCore()}.Build()
+
+	var levels []xlog.LoggerLevel
+	assert.Equal(t, http.StatusOK, get(t, s, "/loggers", &levels))
+	assert.Contains(t, levels, xlog.LoggerLevel{Name: "governor.log", Level: "info"})
+
+	post := func(form url.Values) int {
+		req, err := http.NewRequest(http.MethodPost, "http://"+s.Info().Address+"/loggers", strings.NewReader(form.Encode()))
+		assert.Nil(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Operator", "alice")
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(t, err) {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusNotFound, post(url.Values{"name": {"missing"}, "level": {"debug"}}))
+	assert.Equal(t, http.StatusBadRequest, post(url.Values{"name": {"governor.log"}, "level": {"verbose"}}))
+	assert.Equal(t, http.StatusBadRequest, post(url.Values{"name": {"governor.log"}, "level": {"debug"}, "ttl": {"soon"}}))
+	assert.Equal(t, http.StatusOK, post(url.Values{"name": {"governor.log"}, "level": {"debug"}, "ttl": {"1h"}}))
+	assert.Equal(t, xlog.DebugLevel, logger.Level())
+}
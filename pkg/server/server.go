@@ -5,6 +5,7 @@ import (
 	"copy/constant"
 	"fmt"
 	"github.com/douyu/jupiter/pkg"
+	"net"
 )
 
 type Option func(c *ServiceInfo)
@@ -51,6 +52,18 @@ type Server interface {
 	Info() *ServiceInfo
 }
 
+// ListenerExporter is an optional capability of Server, servers implementing it
+// hand their listener over to the new process on hot restart.
+type ListenerExporter interface {
+	Listener() net.Listener
+}
+
+// ReadyNotifier is an optional capability of Server, Ready is closed once Serve accepts
+// requests. Such a server is registered after it is ready, others right after Serve is called.
+type ReadyNotifier interface {
+	Ready() <-chan struct{}
+}
+
 type Route struct {
 	//权重组
 	WeightGroups []WeightGroup
@@ -0,0 +1,19 @@
+// +build !windows
+
+package signals
+
+import (
+	"os"
+	"syscall"
+)
+
+var shutdownSignals = []os.Signal{syscall.SIGQUIT, os.Interrupt}
+
+var (
+	// SignalReload reloads configuration and reopens log files
+	SignalReload os.Signal = syscall.SIGHUP
+	// SignalDump writes a goroutine and heap dump
+	SignalDump os.Signal = syscall.SIGUSR1
+	// SignalLevel cycles the level of the default logger
+	SignalLevel os.Signal = syscall.SIGUSR2
+)
@@ -0,0 +1,17 @@
+// +build windows
+
+package signals
+
+import (
+	"os"
+	"syscall"
+)
+
+var shutdownSignals = []os.Signal{syscall.SIGQUIT, os.Interrupt}
+
+// there are no user signals on windows, actions of nil signals are never triggered
+var (
+	SignalReload os.Signal = syscall.SIGHUP
+	SignalDump   os.Signal
+	SignalLevel  os.Signal
+)
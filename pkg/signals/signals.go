@@ -0,0 +1,212 @@
+package signals
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Source delivers signals to channels, the same way as os/signal.
+type Source interface {
+	Notify(c chan<- os.Signal, sig ...os.Signal)
+	Stop(c chan<- os.Signal)
+}
+
+type osSource struct{}
+
+func (osSource) Notify(c chan<- os.Signal, sig ...os.Signal) { signal.Notify(c, sig...) }
+
+func (osSource) Stop(c chan<- os.Signal) { signal.Stop(c) }
+
+// OS is the Source of the signals sent to current process.
+var OS Source = osSource{}
+
+// Action handles a signal.
+type Action func(sig os.Signal)
+
+// Router dispatches signals to their actions.
+type Router struct {
+	mu               sync.Mutex
+	source           Source
+	actions          map[os.Signal]Action
+	observe          func(sig os.Signal)
+	shutdown         func(grace bool)
+	shutting         bool
+	forceExitTimeout time.Duration
+	// forceExit exits the process once the force exit timeout passes, stopped on Stop
+	// or once shutdown returns
+	forceExit *time.Timer
+	exit             func(code int)
+	sig              chan os.Signal
+	done             chan struct{}
+}
+
+// NewRouter creates a Router reading signals from source.
+func NewRouter(source Source) *Router {
+	return &Router{
+		source:  source,
+		actions: make(map[os.Signal]Action),
+		exit:    os.Exit,
+	}
+}
+
+// Handle maps sig to action, a former action of sig is replaced.
+// A nil sig is ignored.
+func (r *Router) Handle(sig os.Signal, action Action) {
+	if sig == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actions[sig] = action
+	if r.sig != nil {
+		r.source.Notify(r.sig, sig)
+	}
+}
+
+// Observe calls fn with every received signal before it is dispatched, such as to count them.
+func (r *Router) Observe(fn func(sig os.Signal)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observe = fn
+}
+
+// HandleShutdown calls stop on the first shutdown signal (SIGQUIT, SIGINT),
+// grace is false for SIGQUIT. The process exits directly on a second shutdown
+// signal, or when the force exit timeout passes after the first one.
+func (r *Router) HandleShutdown(stop func(grace bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shutdown = stop
+	if r.sig != nil {
+		r.source.Notify(r.sig, shutdownSignals...)
+	}
+}
+
+// SetForceExitTimeout sets how long shutdown may take before the process exits, zero waits forever.
+func (r *Router) SetForceExitTimeout(timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.forceExitTimeout = timeout
+}
+
+// Start listens signals in a new goroutine.
+func (r *Router) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sig != nil {
+		return
+	}
+	r.sig = make(chan os.Signal, 2)
+	r.done = make(chan struct{})
+
+	sigs := make([]os.Signal, 0, len(r.actions)+len(shutdownSignals))
+	for sig := range r.actions {
+		sigs = append(sigs, sig)
+	}
+	if r.shutdown != nil {
+		sigs = append(sigs, shutdownSignals...)
+	}
+	r.source.Notify(r.sig, sigs...)
+
+	go func(sig chan os.Signal, done chan struct{}) {
+		for {
+			select {
+			case s := <-sig:
+				r.dispatch(s)
+			case <-done:
+				return
+			}
+		}
+	}(r.sig, r.done)
+}
+
+// Handles reports whether sig is mapped to an action.
+func (r *Router) Handles(sig os.Signal) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.actions[sig]
+	return ok
+}
+
+// Stop stops listening signals and a pending force exit.
+func (r *Router) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopForceExit()
+	if r.sig == nil {
+		return
+	}
+	r.source.Stop(r.sig)
+	close(r.done)
+	r.sig = nil
+}
+
+func (r *Router) dispatch(s os.Signal) {
+	// actions are called without r.mu, they may use the router
+	r.mu.Lock()
+	observe, shutdown, action := r.observe, r.shutdown, r.actions[s]
+	shutting := shutdown != nil && isShutdown(s)
+	first := shutting && !r.shutting
+	exit := shutting && r.shutting
+	if first {
+		r.shutting = true
+		if r.forceExitTimeout > 0 {
+			r.forceExit = time.AfterFunc(r.forceExitTimeout, func() {
+				r.exit(exitCode(s))
+			})
+		}
+	}
+	r.mu.Unlock()
+
+	if observe != nil {
+		observe(s)
+	}
+	switch {
+	case exit:
+		// second signal. Exit directly.
+		r.exit(exitCode(s))
+	case first:
+		go func() {
+			shutdown(s != syscall.SIGQUIT)
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.stopForceExit()
+		}()
+	case !shutting && action != nil:
+		go action(s)
+	}
+}
+
+// stopForceExit stops a pending force exit, r.mu is held
+func (r *Router) stopForceExit() {
+	if r.forceExit != nil {
+		r.forceExit.Stop()
+		r.forceExit = nil
+	}
+}
+
+func isShutdown(s os.Signal) bool {
+	for _, sig := range shutdownSignals {
+		if sig == s {
+			return true
+		}
+	}
+	return false
+}
+
+func exitCode(s os.Signal) int {
+	if sig, ok := s.(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+	return 1
+}
+
+// Shutdown suport twice signal must exit
+func Shutdown(stop func(grace bool)) {
+	r := NewRouter(OS)
+	r.HandleShutdown(stop)
+	r.Start()
+}
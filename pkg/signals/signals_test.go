@@ -0,0 +1,138 @@
+// +build !windows
+
+package signals
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSource struct {
+	mu   sync.Mutex
+	subs map[os.Signal]chan<- os.Signal
+}
+
+func (f *fakeSource) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range sig {
+		f.subs[s] = c
+	}
+}
+
+func (f *fakeSource) Stop(c chan<- os.Signal) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for s, sub := range f.subs {
+		if sub == c {
+			delete(f.subs, s)
+		}
+	}
+}
+
+func (f *fakeSource) send(s os.Signal) bool {
+	f.mu.Lock()
+	c, ok := f.subs[s]
+	f.mu.Unlock()
+	if ok {
+		c <- s
+	}
+	return ok
+}
+
+func newTestRouter() (*Router, *fakeSource, chan int) {
+	source := &fakeSource{subs: make(map[os.Signal]chan<- os.Signal)}
+	exited := make(chan int, 2)
+	r := NewRouter(source)
+	r.exit = func(code int) { exited <- code }
+	return r, source, exited
+}
+
+func TestRouter_Action(t *testing.T) {
+	r, source, _ := newTestRouter()
+	got := make(chan os.Signal, 1)
+	r.Handle(syscall.SIGUSR1, func(s os.Signal) { got <- s })
+	r.Start()
+	defer r.Stop()
+
+	// handled after start
+	r.Handle(syscall.SIGHUP, func(s os.Signal) { got <- s })
+
+	assert.True(t, source.send(syscall.SIGUSR1))
+	assert.Equal(t, syscall.SIGUSR1, <-got)
+	assert.True(t, source.send(syscall.SIGHUP))
+	assert.Equal(t, syscall.SIGHUP, <-got)
+	assert.False(t, source.send(syscall.SIGUSR2))
+}
+
+func TestRouter_Shutdown(t *testing.T) {
+	r, source, exited := newTestRouter()
+	grace := make(chan bool, 2)
+	r.HandleShutdown(func(g bool) { grace <- g })
+	r.Start()
+	defer r.Stop()
+
+	// SIGTERM is not a shutdown signal
+	assert.False(t, source.send(syscall.SIGTERM))
+	source.send(syscall.SIGINT)
+	assert.True(t, <-grace)
+
+	source.send(syscall.SIGQUIT)
+	assert.Equal(t, 128+int(syscall.SIGQUIT), <-exited)
+	assert.Equal(t, 0, len(grace))
+}
+
+func TestRouter_ForceExitTimeout(t *testing.T) {
+	r, source, exited := newTestRouter()
+	r.HandleShutdown(func(bool) { select {} })
+	r.SetForceExitTimeout(10 * time.Millisecond)
+	r.Start()
+	defer r.Stop()
+
+	source.send(syscall.SIGQUIT)
+	select {
+	case code := <-exited:
+		assert.Equal(t, 128+int(syscall.SIGQUIT), code)
+	case <-time.After(time.Second):
+		t.Fatal("not exited after force exit timeout")
+	}
+}
+
+func TestRouter_ForceExitStopped(t *testing.T) {
+	r, source, exited := newTestRouter()
+	r.HandleShutdown(func(bool) {})
+	r.SetForceExitTimeout(20 * time.Millisecond)
+	r.Start()
+	defer r.Stop()
+
+	// shutdown returned in time, the process is not exited later
+	source.send(syscall.SIGINT)
+	select {
+	case code := <-exited:
+		t.Fatalf("exited with %d after shutdown", code)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRouter_ObserveUnlocked(t *testing.T) {
+	r, source, _ := newTestRouter()
+	observed := make(chan bool, 1)
+	// the router is usable from the observer
+	r.Observe(func(sig os.Signal) { observed <- r.Handles(sig) })
+	r.Handle(syscall.SIGUSR1, func(os.Signal) {})
+	r.Start()
+	defer r.Stop()
+
+	source.send(syscall.SIGUSR1)
+	select {
+	case handled := <-observed:
+		assert.True(t, handled)
+	case <-time.After(time.Second):
+		t.Fatal("observer blocked")
+	}
+}
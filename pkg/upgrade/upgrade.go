@@ -0,0 +1,230 @@
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// EnvListeners lists the inherited listeners as network://address, in fd order starting at 3
+	EnvListeners = "JUPITER_UPGRADE_LISTENERS"
+	// EnvReadyFD is the fd the child writes to once it is ready
+	EnvReadyFD = "JUPITER_UPGRADE_READY_FD"
+
+	firstFD = 3
+)
+
+type filer interface {
+	File() (*os.File, error)
+}
+
+var (
+	inheritOnce sync.Once
+	inheritMu   sync.Mutex
+	inherited   map[string]*os.File
+	readyFile   *os.File
+)
+
+func inherit() {
+	inheritOnce.Do(func() {
+		inherited = make(map[string]*os.File)
+		if addrs := os.Getenv(EnvListeners); addrs != "" {
+			for i, addr := range strings.Split(addrs, ",") {
+				inherited[addr] = os.NewFile(uintptr(firstFD+i), addr)
+			}
+		}
+		if fd, err := strconv.Atoi(os.Getenv(EnvReadyFD)); err == nil {
+			readyFile = os.NewFile(uintptr(fd), "upgrade-ready")
+		}
+		os.Unsetenv(EnvListeners)
+		os.Unsetenv(EnvReadyFD)
+	})
+}
+
+// IsChild reports whether current process was started by Upgrade.
+func IsChild() bool {
+	inherit()
+	return readyFile != nil
+}
+
+// Listen returns the listener inherited from the parent process for addr,
+// or a new listener when there is none.
+func Listen(network, addr string) (net.Listener, error) {
+	inherit()
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+
+	for key, f := range inherited {
+		if !sameAddr(key, network, addr) {
+			continue
+		}
+		delete(inherited, key)
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("inherit listener %s: %w", key, err)
+		}
+		return ln, nil
+	}
+	return net.Listen(network, addr)
+}
+
+// Ready tells the parent process that current process serves, the parent stops after it.
+// Ready does nothing if current process was not started by Upgrade.
+func Ready() error {
+	inherit()
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+
+	if readyFile == nil {
+		return nil
+	}
+	// listeners never asked for by the new binary are closed here
+	for key, f := range inherited {
+		_ = f.Close()
+		delete(inherited, key)
+	}
+	_, err := readyFile.Write([]byte{1})
+	_ = readyFile.Close()
+	readyFile = nil
+	return err
+}
+
+// Upgrade starts a new process of the running binary with the same arguments,
+// hands listeners to it, and waits until the new process calls Ready.
+// The new process is killed if it exits or ctx is done before it is ready.
+func Upgrade(ctx context.Context, listeners ...net.Listener) (*os.Process, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*os.File, 0, len(listeners)+1)
+	addrs := make([]string, 0, len(listeners))
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, ln := range listeners {
+		lf, ok := ln.(filer)
+		if !ok {
+			return nil, fmt.Errorf("listener %s can not export file", ln.Addr())
+		}
+		f, err := lf.File()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		addrs = append(addrs, ln.Addr().Network()+"://"+ln.Addr().String())
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	files = append(files, w)
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(environ(),
+		EnvListeners+"="+strings.Join(addrs, ","),
+		EnvReadyFD+"="+strconv.Itoa(firstFD+len(addrs)),
+	)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := r.Read(buf)
+		ready <- err
+	}()
+
+	// the child holds its own copy of the pipe, close ours so Read sees EOF if it dies
+	_ = w.Close()
+	files = files[:len(files)-1]
+
+	select {
+	case err := <-ready:
+		if err == nil {
+			return cmd.Process, nil
+		}
+		_ = cmd.Process.Kill()
+		return nil, fmt.Errorf("upgrade child not ready: %w", err)
+	case err := <-exited:
+		if err == nil {
+			err = errors.New("exit status 0")
+		}
+		return nil, fmt.Errorf("upgrade child exited: %w", err)
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		return nil, ctx.Err()
+	}
+}
+
+func environ() []string {
+	env := make([]string, 0)
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, EnvListeners+"=") || strings.HasPrefix(kv, EnvReadyFD+"=") {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// sameAddr compares an inherited network://address key with the requested address,
+// unspecified hosts such as ":8080" and "0.0.0.0:8080" are taken as equal.
+func sameAddr(key, network, addr string) bool {
+	parts := strings.SplitN(key, "://", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	if !strings.HasPrefix(parts[0], strings.TrimRight(network, "46")) {
+		return false
+	}
+	if parts[1] == addr {
+		return true
+	}
+	if !strings.HasPrefix(network, "tcp") {
+		return false
+	}
+
+	host, port, err := net.SplitHostPort(parts[1])
+	if err != nil {
+		return false
+	}
+	wantHost, wantPort, err := net.SplitHostPort(addr)
+	if err != nil || port != wantPort {
+		return false
+	}
+	if host == wantHost {
+		return true
+	}
+	return unspecified(host) && unspecified(wantHost)
+}
+
+func unspecified(host string) bool {
+	if host == "" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
@@ -0,0 +1,95 @@
+package upgrade
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const envTestChild = "UPGRADE_TEST_CHILD"
+
+// TestMain turns the test binary into the upgraded child when started by Upgrade.
+func TestMain(m *testing.M) {
+	if os.Getenv(envTestChild) == "" {
+		os.Exit(m.Run())
+	}
+
+	if !IsChild() {
+		os.Exit(2)
+	}
+	ln, err := Listen("tcp", os.Getenv(envTestChild))
+	if err != nil {
+		os.Exit(3)
+	}
+	if os.Getenv(envTestChild+"_FAIL") != "" {
+		os.Exit(4)
+	}
+	if err := Ready(); err != nil {
+		os.Exit(5)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		os.Exit(6)
+	}
+	fmt.Fprintf(conn, "child %d\n", os.Getpid())
+	conn.Close()
+	os.Exit(0)
+}
+
+func TestUpgrade(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	os.Setenv(envTestChild, ln.Addr().String())
+	defer os.Unsetenv(envTestChild)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	proc, err := Upgrade(ctx, ln)
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	defer proc.Wait()
+
+	// parent stops accepting, the inherited socket keeps serving in the child
+	ln.Close()
+	conn, err := net.Dial("tcp", os.Getenv(envTestChild))
+	assert.Nil(t, err)
+	defer conn.Close()
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf("child %d\n", proc.Pid), line)
+}
+
+func TestUpgrade_ChildExit(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	os.Setenv(envTestChild, ln.Addr().String())
+	os.Setenv(envTestChild+"_FAIL", "1")
+	defer os.Unsetenv(envTestChild)
+	defer os.Unsetenv(envTestChild + "_FAIL")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = Upgrade(ctx, ln)
+	assert.NotNil(t, err)
+}
+
+func TestSameAddr(t *testing.T) {
+	assert.True(t, sameAddr("tcp://[::]:8080", "tcp", ":8080"))
+	assert.True(t, sameAddr("tcp://0.0.0.0:8080", "tcp4", "0.0.0.0:8080"))
+	assert.True(t, sameAddr("tcp://127.0.0.1:8080", "tcp", "127.0.0.1:8080"))
+	assert.False(t, sameAddr("tcp://127.0.0.1:8080", "tcp", ":8080"))
+	assert.False(t, sameAddr("tcp://[::]:8080", "tcp", ":8081"))
+	assert.True(t, sameAddr("unix:///tmp/app.sock", "unix", "/tmp/app.sock"))
+}
@@ -1,6 +1,10 @@
 package xdefer
 
-import "sync"
+import (
+	"sync"
+
+	"go.uber.org/multierr"
+)
 
 type DeferStack struct {
 	fns []func() error
@@ -20,10 +24,13 @@ func (ds *DeferStack) Push(fns ...func() error) {
 	ds.fns = append(ds.fns, fns...)
 }
 
-func (ds *DeferStack) Clean() {
+// Clean runs fns in reverse push order, errors of all fns are combined.
+func (ds *DeferStack) Clean() error {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
+	var errs error
 	for i := len(ds.fns) - 1; i >= 0; i-- {
-		_ = ds.fns[i]()
+		errs = multierr.Append(errs, ds.fns[i]())
 	}
+	return errs
 }
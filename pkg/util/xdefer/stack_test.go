@@ -1,6 +1,7 @@
 package xdefer
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -32,3 +33,19 @@ func TestNewStack(t *testing.T) {
 	}
 
 }
+
+func TestStack_CleanErr(t *testing.T) {
+	stack := NewStack()
+	stack.Push(func() error {
+		return errors.New("close db")
+	}, func() error {
+		return nil
+	}, func() error {
+		return errors.New("flush log")
+	})
+	err := stack.Clean()
+	want := "flush log; close db"
+	if err == nil || err.Error() != want {
+		t.Fatalf("Stack clean error,want:%v ret:%v", want, err)
+	}
+}
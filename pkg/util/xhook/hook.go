@@ -0,0 +1,130 @@
+package xhook
+
+import (
+	"context"
+	"copy/pkg/util/xstage"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// Policy decides what happens to the remaining hooks when a hook fails.
+type Policy int
+
+const (
+	// PolicyContinue runs the remaining hooks after the hook fails
+	PolicyContinue Policy = iota
+	// PolicyAbort skips the remaining hooks and aborts the stage
+	PolicyAbort
+)
+
+// Hook is a named func run at a lifecycle stage.
+type Hook struct {
+	Name string
+	// Timeout bounds the hook run, zero means no limit
+	Timeout time.Duration
+	Policy  Policy
+	Fn      func(ctx context.Context) error
+}
+
+// Func wraps fn into a hook named after the func, with PolicyContinue.
+func Func(fn func() error) *Hook {
+	return &Hook{
+		Name: xstage.FuncName(fn),
+		Fn: func(context.Context) error {
+			return fn()
+		},
+	}
+}
+
+// Error reports the hook a failure belongs to.
+type Error struct {
+	Hook string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("hook %s: %v", e.Hook, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Result is the outcome of one hook run.
+type Result struct {
+	Name string
+	Cost time.Duration
+	Err  error
+}
+
+// Report is the outcome of a Hooks run.
+type Report struct {
+	Results []Result
+	// Aborted is true when a PolicyAbort hook failed
+	Aborted bool
+}
+
+// Err combines the error of every failed hook, nil if all hooks succeeded.
+func (r *Report) Err() error {
+	var errs error
+	for _, result := range r.Results {
+		if result.Err != nil {
+			errs = multierr.Append(errs, &Error{Hook: result.Name, Err: result.Err})
+		}
+	}
+	return errs
+}
+
+// Hooks is an ordered list of hooks.
+type Hooks struct {
+	mu    sync.Mutex
+	hooks []*Hook
+	lifo  bool
+}
+
+// NewQueue creates Hooks run in push order.
+func NewQueue() *Hooks {
+	return &Hooks{hooks: make([]*Hook, 0)}
+}
+
+// NewStack creates Hooks run in reverse push order.
+func NewStack() *Hooks {
+	return &Hooks{hooks: make([]*Hook, 0), lifo: true}
+}
+
+// Push ...
+func (h *Hooks) Push(hooks ...*Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks = append(h.hooks, hooks...)
+}
+
+// Run runs hooks one by one.
+func (h *Hooks) Run(ctx context.Context) *Report {
+	h.mu.Lock()
+	hooks := make([]*Hook, len(h.hooks))
+	copy(hooks, h.hooks)
+	h.mu.Unlock()
+
+	if h.lifo {
+		for i, j := 0, len(hooks)-1; i < j; i, j = i+1, j-1 {
+			hooks[i], hooks[j] = hooks[j], hooks[i]
+		}
+	}
+
+	report := &Report{Results: make([]Result, 0, len(hooks))}
+	for _, hook := range hooks {
+		start := time.Now()
+		err := xstage.RunTimeout(ctx, hook.Timeout, hook.Fn)
+		report.Results = append(report.Results, Result{Name: hook.Name, Cost: time.Since(start), Err: err})
+		if err != nil && hook.Policy == PolicyAbort {
+			report.Aborted = true
+			break
+		}
+	}
+	return report
+}
@@ -0,0 +1,71 @@
+package xhook
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks_Order(t *testing.T) {
+	state := ""
+	push := func(h *Hooks) {
+		for _, s := range []string{"1", "2", "3"} {
+			s := s
+			h.Push(&Hook{Name: s, Fn: func(context.Context) error {
+				state += s
+				return nil
+			}})
+		}
+	}
+
+	queue := NewQueue()
+	push(queue)
+	report := queue.Run(context.Background())
+	assert.Nil(t, report.Err())
+	assert.Equal(t, "123", state)
+
+	state = ""
+	stack := NewStack()
+	push(stack)
+	stack.Run(context.Background())
+	assert.Equal(t, "321", state)
+}
+
+func TestHooks_Policy(t *testing.T) {
+	ran := false
+	hooks := NewQueue()
+	hooks.Push(
+		&Hook{Name: "flush", Fn: func(context.Context) error { return errors.New("disk full") }},
+		&Hook{Name: "close", Policy: PolicyAbort, Fn: func(context.Context) error { return errors.New("closed") }},
+		&Hook{Name: "notify", Fn: func(context.Context) error { ran = true; return nil }},
+	)
+	report := hooks.Run(context.Background())
+	assert.True(t, report.Aborted)
+	assert.False(t, ran)
+	assert.Equal(t, 2, len(report.Results))
+
+	err := report.Err()
+	assert.True(t, strings.Contains(err.Error(), "hook flush: disk full"))
+	assert.True(t, strings.Contains(err.Error(), "hook close: closed"))
+}
+
+func TestHooks_Timeout(t *testing.T) {
+	hooks := NewQueue()
+	hooks.Push(&Hook{Name: "slow", Timeout: 10 * time.Millisecond, Fn: func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}})
+	report := hooks.Run(context.Background())
+	assert.True(t, errors.Is(report.Err(), context.DeadlineExceeded))
+	assert.True(t, report.Results[0].Cost < time.Second)
+}
+
+func TestFunc(t *testing.T) {
+	hook := Func(func() error { return nil })
+	assert.True(t, strings.HasPrefix(hook.Name, "copy/pkg/util/xhook.TestFunc"))
+	assert.Equal(t, PolicyContinue, hook.Policy)
+}
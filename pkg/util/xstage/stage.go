@@ -0,0 +1,290 @@
+package xstage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// Stage is a named startup step. A stage starts once every stage named in Deps
+// has finished successfully, stages without a dependency between them run in parallel.
+type Stage struct {
+	Name string
+	Deps []string
+	// Timeout bounds the stage run, zero means no limit
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+	// Undo rolls the stage back when a later stage fails, optional
+	Undo func() error
+}
+
+// Error reports the stage a failure belongs to.
+type Error struct {
+	Stage string
+	// Undo is true when the error comes from the stage undo function
+	Undo bool
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Undo {
+		return fmt.Sprintf("stage %s undo: %v", e.Stage, e.Err)
+	}
+	return fmt.Sprintf("stage %s: %v", e.Stage, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Graph is a set of stages resolved as a DAG.
+type Graph struct {
+	mu     sync.Mutex
+	stages map[string]*Stage
+	names  []string
+}
+
+// New creates a Graph with the given stages.
+func New(stages ...*Stage) (*Graph, error) {
+	g := &Graph{
+		stages: make(map[string]*Stage),
+		names:  make([]string, 0),
+	}
+	if err := g.Add(stages...); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Add adds stages into graph, stage name must be unique.
+func (g *Graph) Add(stages ...*Stage) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, s := range stages {
+		if s.Name == "" {
+			return fmt.Errorf("stage name empty")
+		}
+		if s.Run == nil {
+			return fmt.Errorf("stage %s: run func nil", s.Name)
+		}
+		if _, ok := g.stages[s.Name]; ok {
+			return fmt.Errorf("stage %s: duplicate name", s.Name)
+		}
+		g.stages[s.Name] = s
+		g.names = append(g.names, s.Name)
+	}
+	return nil
+}
+
+// Order returns stage names in a dependency respecting order, or an error
+// when a dependency is unknown or stages depend on each other in a cycle.
+func (g *Graph) Order() ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.order()
+}
+
+func (g *Graph) order() ([]string, error) {
+	indegree := make(map[string]int, len(g.stages))
+	children := make(map[string][]string, len(g.stages))
+	for _, name := range g.names {
+		for _, dep := range g.stages[name].Deps {
+			if _, ok := g.stages[dep]; !ok {
+				return nil, fmt.Errorf("stage %s: unknown dependency %s", name, dep)
+			}
+			indegree[name]++
+			children[dep] = append(children[dep], name)
+		}
+	}
+
+	queue := make([]string, 0, len(g.names))
+	for _, name := range g.names {
+		if indegree[name] == 0 {
+			queue = append(queue, name)
+		}
+	}
+	order := make([]string, 0, len(g.names))
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		order = append(order, name)
+		for _, child := range children[name] {
+			indegree[child]--
+			if indegree[child] == 0 {
+				queue = append(queue, child)
+			}
+		}
+	}
+	if len(order) != len(g.names) {
+		cycle := make([]string, 0)
+		for _, name := range g.names {
+			if indegree[name] > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		return nil, fmt.Errorf("stage dependency cycle: %v", cycle)
+	}
+	return order, nil
+}
+
+type result struct {
+	name string
+	err  error
+}
+
+// Run runs every stage as soon as its dependencies are done.
+// After the first failure no new stage is started, running stages get their
+// context canceled, and the undo functions of finished stages run in reverse
+// finish order. The returned error combines every *Error.
+func (g *Graph) Run(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, err := g.order(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		pending  = make(map[string]int, len(g.stages))
+		children = make(map[string][]string, len(g.stages))
+		results  = make(chan result, len(g.stages))
+		finished = make([]string, 0, len(g.stages))
+		running  = 0
+		errs     error
+	)
+	for _, name := range g.names {
+		pending[name] = len(g.stages[name].Deps)
+		for _, dep := range g.stages[name].Deps {
+			children[dep] = append(children[dep], name)
+		}
+	}
+
+	start := func(name string) {
+		running++
+		go func(s *Stage) {
+			results <- result{name: s.Name, err: runStage(ctx, s)}
+		}(g.stages[name])
+	}
+	for _, name := range g.names {
+		if pending[name] == 0 {
+			start(name)
+		}
+	}
+
+	for running > 0 {
+		r := <-results
+		running--
+		if r.err != nil {
+			// stages canceled by an earlier failure are not failures of their own
+			if errs != nil && errors.Is(r.err, context.Canceled) {
+				continue
+			}
+			errs = multierr.Append(errs, &Error{Stage: r.name, Err: r.err})
+			cancel()
+			continue
+		}
+		finished = append(finished, r.name)
+		if errs != nil {
+			continue
+		}
+		for _, child := range children[r.name] {
+			pending[child]--
+			if pending[child] == 0 {
+				start(child)
+			}
+		}
+	}
+
+	if errs != nil {
+		for i := len(finished) - 1; i >= 0; i-- {
+			s := g.stages[finished[i]]
+			if s.Undo == nil {
+				continue
+			}
+			if err := s.Undo(); err != nil {
+				errs = multierr.Append(errs, &Error{Stage: s.Name, Undo: true, Err: err})
+			}
+		}
+	}
+	return errs
+}
+
+func runStage(ctx context.Context, s *Stage) error {
+	return RunTimeout(ctx, s.Timeout, s.Run)
+}
+
+// RunTimeout runs fn bounded by timeout unless it is zero, a panic of fn is returned
+// as an error. It returns once ctx is done without waiting for fn.
+func RunTimeout(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timeout after %v: %w", timeout, ctx.Err())
+		}
+		return ctx.Err()
+	}
+}
+
+// Serial wraps plain funcs into stages that keep call order, each stage
+// depends on the previous one and is named after the func.
+func Serial(fns ...func() error) []*Stage {
+	stages := make([]*Stage, 0, len(fns))
+	seen := make(map[string]int, len(fns))
+	for i, fn := range fns {
+		fn := fn
+		base := FuncName(fn)
+		name := base
+		if n := seen[base]; n > 0 {
+			name = fmt.Sprintf("%s#%d", base, n)
+		}
+		seen[base]++
+
+		stage := &Stage{
+			Name: name,
+			Run: func(context.Context) error {
+				return fn()
+			},
+		}
+		if i > 0 {
+			stage.Deps = []string{stages[i-1].Name}
+		}
+		stages = append(stages, stage)
+	}
+	return stages
+}
+
+// FuncName returns the full name of fn.
+func FuncName(fn interface{}) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return fmt.Sprintf("%p", fn)
+}
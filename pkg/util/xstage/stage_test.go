@@ -0,0 +1,155 @@
+package xstage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) add(s string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, s)
+}
+
+func (r *recorder) index(s string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, c := range r.calls {
+		if c == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *recorder) stage(name string, err error, deps ...string) *Stage {
+	return &Stage{
+		Name: name,
+		Deps: deps,
+		Run: func(context.Context) error {
+			r.add(name)
+			return err
+		},
+		Undo: func() error {
+			r.add("undo." + name)
+			return nil
+		},
+	}
+}
+
+func TestGraph_RunOrder(t *testing.T) {
+	r := &recorder{}
+	g, err := New(
+		r.stage("registry", nil, "logger", "config"),
+		r.stage("logger", nil, "config"),
+		r.stage("db", nil, "config"),
+		r.stage("config", nil),
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, g.Run(context.Background()))
+
+	assert.Equal(t, 4, len(r.calls))
+	assert.Equal(t, 0, r.index("config"))
+	assert.True(t, r.index("logger") < r.index("registry"))
+}
+
+func TestGraph_RunParallel(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	wait := func(context.Context) error {
+		wg.Done()
+		wg.Wait()
+		return nil
+	}
+	g, _ := New(&Stage{Name: "a", Run: wait}, &Stage{Name: "b", Run: wait})
+
+	done := make(chan error)
+	go func() { done <- g.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("independent stages not run in parallel")
+	}
+}
+
+func TestGraph_RunFailure(t *testing.T) {
+	r := &recorder{}
+	g, _ := New(
+		r.stage("config", nil),
+		r.stage("logger", nil, "config"),
+		r.stage("db", errors.New("dial timeout"), "logger"),
+		r.stage("registry", nil, "db"),
+	)
+	err := g.Run(context.Background())
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "stage db: dial timeout"))
+
+	var se *Error
+	assert.True(t, errors.As(err, &se))
+	assert.Equal(t, "db", se.Stage)
+
+	assert.Equal(t, -1, r.index("registry"))
+	assert.Equal(t, -1, r.index("undo.db"))
+	assert.True(t, r.index("undo.logger") < r.index("undo.config"))
+}
+
+func TestGraph_RunTimeout(t *testing.T) {
+	g, _ := New(&Stage{
+		Name:    "slow",
+		Timeout: 10 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		},
+	})
+	err := g.Run(context.Background())
+	assert.NotNil(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestGraph_Order(t *testing.T) {
+	run := func(context.Context) error { return nil }
+
+	g, _ := New(&Stage{Name: "a", Deps: []string{"b"}, Run: run}, &Stage{Name: "b", Deps: []string{"a"}, Run: run})
+	_, err := g.Order()
+	assert.NotNil(t, err)
+
+	g, _ = New(&Stage{Name: "a", Deps: []string{"missing"}, Run: run})
+	assert.NotNil(t, g.Run(context.Background()))
+
+	_, err = New(&Stage{Name: "a", Run: run}, &Stage{Name: "a", Run: run})
+	assert.NotNil(t, err)
+}
+
+func TestSerial(t *testing.T) {
+	var state string
+	fn := func() error {
+		state += "1"
+		return nil
+	}
+	stages := Serial(fn, fn, func() error {
+		state += "2"
+		return nil
+	})
+	assert.Equal(t, 3, len(stages))
+	assert.Equal(t, stages[0].Name+"#1", stages[1].Name)
+	assert.Equal(t, []string{stages[1].Name}, stages[2].Deps)
+
+	g, err := New(stages...)
+	assert.Nil(t, err)
+	assert.Nil(t, g.Run(context.Background()))
+	assert.Equal(t, "112", state)
+}
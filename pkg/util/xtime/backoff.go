@@ -0,0 +1,57 @@
+// Copyright 2020 Douyu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xtime
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// maxDuration is the longest time.Duration
+const maxDuration = time.Duration(math.MaxInt64)
+
+// Backoff is an exponential backoff: the wait before a retry grows from Min by
+// Multiplier up to Max, zero Max grows up to the longest duration. Jitter
+// randomizes each wait by this fraction, in [0,1].
+type Backoff struct {
+	Min        time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// Duration returns the wait before the retry after attempt failures, counted from 0
+func (b Backoff) Duration(attempt int) time.Duration {
+	backoff := float64(b.Min)
+	if b.Multiplier > 1 {
+		backoff *= math.Pow(b.Multiplier, float64(attempt))
+	}
+	// math.Pow overflows to +Inf, which is no duration
+	max := maxDuration
+	if b.Max > 0 {
+		max = b.Max
+	}
+	if backoff > float64(max) {
+		backoff = float64(max)
+	}
+	if b.Jitter > 0 {
+		backoff += backoff * b.Jitter * (rand.Float64()*2 - 1)
+	}
+	if backoff >= float64(maxDuration) {
+		return maxDuration
+	}
+	return time.Duration(backoff)
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 Douyu
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff_Duration(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		attempt int
+		want    time.Duration
+	}{
+		{"min", Backoff{Min: 10 * time.Millisecond, Max: 50 * time.Millisecond, Multiplier: 2}, 0, 10 * time.Millisecond},
+		{"grown", Backoff{Min: 10 * time.Millisecond, Max: 50 * time.Millisecond, Multiplier: 2}, 2, 40 * time.Millisecond},
+		{"max", Backoff{Min: 10 * time.Millisecond, Max: 50 * time.Millisecond, Multiplier: 2}, 5, 50 * time.Millisecond},
+		{"constant", Backoff{Min: 10 * time.Millisecond, Multiplier: 1}, 5, 10 * time.Millisecond},
+		{"no max", Backoff{Min: time.Second, Multiplier: 2}, 10000, maxDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.backoff.Duration(tt.attempt); got != tt.want {
+				t.Errorf("Backoff.Duration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff_Jitter(t *testing.T) {
+	b := Backoff{Min: 100 * time.Millisecond, Multiplier: 2, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		if got := b.Duration(1); got < 160*time.Millisecond || got > 240*time.Millisecond {
+			t.Fatalf("Backoff.Duration() = %v, want within 20%% of 200ms", got)
+		}
+	}
+	b = Backoff{Min: time.Second, Multiplier: 2, Jitter: 1}
+	for i := 0; i < 100; i++ {
+		if got := b.Duration(10000); got < 0 {
+			t.Fatalf("Backoff.Duration() = %v, want no overflow", got)
+		}
+	}
+}
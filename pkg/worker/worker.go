@@ -1,6 +1,15 @@
 package worker
 
+import "context"
+
 type Worker interface {
 	Run() error
 	Stop() error
 }
+
+// ContextWorker is a Worker run with the context of the app instead of Run, the
+// context is cancelled once the app stops or, by default, a sibling task fails.
+type ContextWorker interface {
+	Worker
+	RunContext(ctx context.Context) error
+}
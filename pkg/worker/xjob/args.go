@@ -0,0 +1,160 @@
+package xjob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/douyu/jupiter/pkg/util/xcast"
+)
+
+// ArgType is the type of a job argument.
+type ArgType string
+
+const (
+	ArgString   ArgType = "string"
+	ArgInt      ArgType = "int"
+	ArgFloat    ArgType = "float"
+	ArgBool     ArgType = "bool"
+	ArgDuration ArgType = "duration"
+)
+
+// Arg is an argument of a job, passed by --job-args name=value.
+type Arg struct {
+	Name string
+	// Type is ArgString if empty
+	Type     ArgType
+	Usage    string
+	Default  string
+	Required bool
+}
+
+// Describer is a job which tells its description and the schema of its arguments,
+// they are printed by --list-jobs.
+type Describer interface {
+	Description() string
+	Args() []Arg
+}
+
+// Describe returns the description and the arguments of job, nothing if it is not a Describer.
+func Describe(job RunnerE) (string, []Arg) {
+	if r, ok := job.(runner); ok {
+		d, ok := r.Runner.(Describer)
+		if !ok {
+			return "", nil
+		}
+		return d.Description(), d.Args()
+	}
+	if d, ok := job.(Describer); ok {
+		return d.Description(), d.Args()
+	}
+	return "", nil
+}
+
+// Usage writes the name, the description and the arguments of job to w.
+func Usage(w io.Writer, name string, job RunnerE) {
+	description, args := Describe(job)
+	fmt.Fprintf(w, "%s\t%s\n", name, description)
+	for _, arg := range args {
+		typ := arg.Type
+		if typ == "" {
+			typ = ArgString
+		}
+		fmt.Fprintf(w, "  %s=<%s>\t%s", arg.Name, typ, arg.Usage)
+		if arg.Required {
+			fmt.Fprint(w, " (required)")
+		} else if arg.Default != "" {
+			fmt.Fprintf(w, " (default %s)", arg.Default)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// Args are the arguments of a job run by name, an argument of the schema of the job
+// is of its type, the others are strings.
+type Args map[string]interface{}
+
+// ParseArgs parses pairs of name=value into Args by schema: values are converted to
+// the type of their Arg, defaults fill the missing ones and a missing required Arg is
+// an error. Pairs out of schema are kept as strings.
+func ParseArgs(pairs []string, schema []Arg) (Args, error) {
+	raws := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid job arg %q, want name=value", pair)
+		}
+		raws[kv[0]] = kv[1]
+	}
+
+	args := make(Args, len(raws))
+	for name, raw := range raws {
+		args[name] = raw
+	}
+	for _, arg := range schema {
+		raw, ok := raws[arg.Name]
+		if !ok {
+			if arg.Required {
+				return nil, fmt.Errorf("missing job arg %s", arg.Name)
+			}
+			if arg.Default == "" {
+				continue
+			}
+			raw = arg.Default
+		}
+		value, err := arg.parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid job arg %s: %w", arg.Name, err)
+		}
+		args[arg.Name] = value
+	}
+	return args, nil
+}
+
+func (arg Arg) parse(raw string) (interface{}, error) {
+	switch arg.Type {
+	case ArgString, "":
+		return raw, nil
+	case ArgInt:
+		return strconv.ParseInt(raw, 10, 64)
+	case ArgFloat:
+		return strconv.ParseFloat(raw, 64)
+	case ArgBool:
+		return strconv.ParseBool(raw)
+	case ArgDuration:
+		return time.ParseDuration(raw)
+	default:
+		return nil, fmt.Errorf("unknown type %s", arg.Type)
+	}
+}
+
+// String returns the argument of name as a string.
+func (a Args) String(name string) string { return xcast.ToString(a[name]) }
+
+// Int returns the argument of name as an int64.
+func (a Args) Int(name string) int64 { return xcast.ToInt64(a[name]) }
+
+// Float64 returns the argument of name as a float64.
+func (a Args) Float64(name string) float64 { return xcast.ToFloat64(a[name]) }
+
+// Bool returns the argument of name as a bool.
+func (a Args) Bool(name string) bool { return xcast.ToBool(a[name]) }
+
+// Duration returns the argument of name as a time.Duration.
+func (a Args) Duration(name string) time.Duration { return xcast.ToDuration(a[name]) }
+
+type argsKey struct{}
+
+// WithArgs returns a child of ctx carrying the arguments of a job run.
+func WithArgs(ctx context.Context, args Args) context.Context {
+	return context.WithValue(ctx, argsKey{}, args)
+}
+
+// ArgsFrom returns the arguments of the job run of ctx, nil if there are none.
+func ArgsFrom(ctx context.Context) Args {
+	args, _ := ctx.Value(argsKey{}).(Args)
+	return args
+}
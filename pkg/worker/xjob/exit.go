@@ -0,0 +1,55 @@
+package xjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Exit codes of job runs, see ExitCode. ExitUsage is an unknown job or an invalid
+// job argument.
+const (
+	ExitOK          = 0
+	ExitFailure     = 1
+	ExitUsage       = 2
+	ExitInterrupted = 130
+)
+
+// ExitError is a job error with the exit code of the process.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Exit returns err with the exit code of the process, err may be nil.
+func Exit(code int, err error) error {
+	return &ExitError{Code: code, Err: err}
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit %d", e.Code)
+	}
+	return fmt.Sprintf("exit %d: %v", e.Code, e.Err)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the process of a job error: the code of an
+// ExitError, ExitInterrupted for a cancelled job, ExitFailure for any other error
+// and ExitOK for nil, see Application.RunAndExit.
+func ExitCode(err error) int {
+	var exit *ExitError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &exit):
+		return exit.Code
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	default:
+		return ExitFailure
+	}
+}
@@ -1,17 +1,82 @@
 package xjob
 
-import "copy/pkg/flag"
+import (
+	"context"
+	"copy/pkg/flag"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
 
-func init() {
-	flag.Register(
+// Flags are the flags choosing the jobs run by the app and their arguments, they are
+// registered by the app.
+func Flags() []flag.Flag {
+	return []flag.Flag{
 		&flag.StringFlag{
-			Name:    "job",
-			Usage:   "--job",
-			Default: "",
+			Name:  "job",
+			Usage: "--job=a,b,c, run jobs by name in sequence",
 		},
-	)
+		&flag.BoolFlag{
+			Name:  "job-parallel",
+			Usage: "--job-parallel, run the jobs of --job in parallel",
+		},
+		&flag.StringSliceFlag{
+			Name:  "job-args",
+			Usage: "--job-args name=value, an argument of the jobs, repeatable",
+		},
+		&flag.BoolFlag{
+			Name:  "list-jobs",
+			Usage: "--list-jobs, print the registered jobs and their arguments",
+		},
+	}
 }
 
+// Runner is a job which can not fail nor be cancelled, see RunnerE.
 type Runner interface {
 	Run()
 }
+
+// RunnerE is a job run with a context, which is cancelled once the app is told to
+// stop such as by SIGINT. The error of RunE sets the exit code of the process, see ExitCode.
+type RunnerE interface {
+	RunE(ctx context.Context) error
+}
+
+// Wrap returns r as a RunnerE which ignores ctx and never fails, r itself if it is
+// a RunnerE too.
+func Wrap(r Runner) RunnerE {
+	if e, ok := r.(RunnerE); ok {
+		return e
+	}
+	return runner{r}
+}
+
+type runner struct {
+	Runner
+}
+
+func (r runner) RunE(context.Context) error {
+	r.Run()
+	return nil
+}
+
+type runIDKey struct{}
+
+// NewRunID returns a new run ID, the start time of the run followed by random hex.
+func NewRunID(start time.Time) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%s", start.Format("20060102T150405"), hex.EncodeToString(b))
+}
+
+// WithRunID returns a child of ctx carrying the run ID of a job run.
+func WithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// RunID returns the run ID of the job run of ctx, empty if there is none.
+func RunID(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,81 @@
+package xjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type oldJob struct {
+	runs int
+}
+
+func (j *oldJob) Run() { j.runs++ }
+
+type job struct {
+	oldJob
+}
+
+func (j *job) RunE(ctx context.Context) error {
+	return errors.New("run by RunE")
+}
+
+func TestWrap(t *testing.T) {
+	old := &oldJob{}
+	assert.Nil(t, Wrap(old).RunE(context.Background()))
+	assert.Equal(t, 1, old.runs)
+
+	j := &job{}
+	assert.EqualError(t, Wrap(j).RunE(context.Background()), "run by RunE")
+	assert.Equal(t, 0, j.runs)
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitOK, ExitCode(nil))
+	assert.Equal(t, ExitFailure, ExitCode(errors.New("no rows")))
+	assert.Equal(t, ExitInterrupted, ExitCode(fmt.Errorf("job sync: %w", context.Canceled)))
+	assert.Equal(t, 3, ExitCode(fmt.Errorf("job sync: %w", Exit(3, errors.New("partial")))))
+	assert.EqualError(t, Exit(3, errors.New("partial")), "exit 3: partial")
+}
+
+func TestRunID(t *testing.T) {
+	start := time.Date(2020, 7, 1, 10, 30, 0, 0, time.Local)
+	id := NewRunID(start)
+	assert.True(t, strings.HasPrefix(id, "20200701T103000-"), id)
+	assert.NotEqual(t, id, NewRunID(start))
+	ctx := WithRunID(context.Background(), id)
+	assert.Equal(t, id, RunID(ctx))
+	assert.Empty(t, RunID(context.Background()))
+}
+
+func TestParseArgs(t *testing.T) {
+	schema := []Arg{
+		{Name: "since", Type: ArgDuration, Required: true},
+		{Name: "limit", Type: ArgInt, Default: "100"},
+		{Name: "dry", Type: ArgBool},
+		{Name: "name"},
+	}
+	args, err := ParseArgs([]string{"since=1h", "dry=true", "region=eu=west"}, schema)
+	assert.Nil(t, err)
+	assert.Equal(t, Args{"since": time.Hour, "limit": int64(100), "dry": true, "region": "eu=west"}, args)
+	assert.Equal(t, "1h0m0s", args.String("since"))
+	assert.Equal(t, time.Hour, args.Duration("since"))
+	assert.Equal(t, float64(100), args.Float64("limit"))
+	assert.Empty(t, args.String("name"))
+
+	_, err = ParseArgs([]string{"limit=1"}, schema)
+	assert.EqualError(t, err, "missing job arg since")
+	_, err = ParseArgs([]string{"since=1h", "limit=many"}, schema)
+	assert.Contains(t, err.Error(), "invalid job arg limit")
+	_, err = ParseArgs([]string{"since"}, schema)
+	assert.EqualError(t, err, `invalid job arg "since", want name=value`)
+
+	ctx := WithArgs(context.Background(), args)
+	assert.Equal(t, args, ArgsFrom(ctx))
+	assert.Nil(t, ArgsFrom(context.Background()))
+}
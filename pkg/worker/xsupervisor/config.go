@@ -0,0 +1,86 @@
+package xsupervisor
+
+import (
+	"copy/pkg/conf"
+	"copy/pkg/worker"
+	"copy/pkg/xlog"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Policy decides whether a worker is restarted after Run returns.
+type Policy string
+
+const (
+	// PolicyNever never restarts the worker
+	PolicyNever Policy = "never"
+	// PolicyOnFailure restarts the worker when Run returns an error or panics
+	PolicyOnFailure Policy = "on-failure"
+	// PolicyAlways restarts the worker whenever Run returns
+	PolicyAlways Policy = "always"
+)
+
+type Config struct {
+	// Name shows in logs and status, the worker type name by default
+	Name   string
+	Policy Policy `validate:"oneof=never on-failure always"`
+	// restart backoff, see xtime.Backoff
+	MinBackoff time.Duration `validate:"min=0s"`
+	MaxBackoff time.Duration `validate:"min=0s"`
+	Multiplier float64       `validate:"min=1"`
+	Jitter     float64       `validate:"min=0,max=1"`
+	// at most MaxRestarts restarts within Window, zero means unlimited
+	MaxRestarts int           `validate:"min=0"`
+	Window      time.Duration `validate:"min=0s"`
+	logger      *xlog.Logger
+}
+
+// RawConfig ...
+func RawConfig(key string) *Config {
+	var config = DefaultConfig()
+	if err := conf.UnmarshalKey(key, config); err != nil && !conf.IsValidationError(err) && !errors.Is(err, conf.ErrInvalidKey) {
+		panic(err)
+	}
+	return config
+}
+
+// StdConfig Jupiter Standard supervisor config
+func StdConfig(name string) *Config {
+	config := RawConfig("jupiter.worker.supervisor." + name)
+	if config.Name == "" {
+		config.Name = name
+	}
+	return config
+}
+
+// DefaultConfig ...
+func DefaultConfig() *Config {
+	return &Config{
+		Policy:      PolicyOnFailure,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+		Multiplier:  2,
+		Jitter:      0.2,
+		MaxRestarts: 5,
+		Window:      time.Minute,
+		logger:      xlog.JupiterLogger,
+	}
+}
+
+// WithLogger ...
+func (config *Config) WithLogger(logger *xlog.Logger) *Config {
+	config.logger = logger
+	return config
+}
+
+// Build wraps w into a Supervisor.
+func (config Config) Build(w worker.Worker) *Supervisor {
+	if config.Name == "" {
+		config.Name = fmt.Sprintf("%T", w)
+	}
+	if config.logger == nil {
+		config.logger = xlog.JupiterLogger
+	}
+	return newSupervisor(&config, w)
+}
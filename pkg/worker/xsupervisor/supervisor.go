@@ -0,0 +1,205 @@
+package xsupervisor
+
+import (
+	"context"
+	"copy/pkg/worker"
+	"copy/pkg/util/xtime"
+	"copy/pkg/xlog"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const modSupervisor = "worker.supervisor"
+
+// ErrRestartBudget is returned by Run when the worker fails more than MaxRestarts times within Window.
+var ErrRestartBudget = errors.New("restart budget exhausted")
+
+// PanicError is the error a recovered worker panic turns into.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("worker panic: %v", e.Value)
+}
+
+// Status is a snapshot of a supervised worker.
+type Status struct {
+	Name      string    `json:"name"`
+	Policy    Policy    `json:"policy"`
+	Running   bool      `json:"running"`
+	Restarts  int       `json:"restarts"`
+	LastError string    `json:"lastError"`
+	LastStart time.Time `json:"lastStart"`
+	LastExit  time.Time `json:"lastExit"`
+}
+
+// Supervisor runs a worker and restarts it according to its Policy.
+// Supervisor implements worker.ContextWorker itself.
+type Supervisor struct {
+	config *Config
+	worker worker.Worker
+
+	mu       sync.RWMutex
+	status   Status
+	restarts []time.Time
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+func newSupervisor(config *Config, w worker.Worker) *Supervisor {
+	return &Supervisor{
+		config:   config,
+		worker:   w,
+		restarts: make([]time.Time, 0),
+		stop:     make(chan struct{}),
+		status: Status{
+			Name:   config.Name,
+			Policy: config.Policy,
+		},
+	}
+}
+
+// Run runs the worker until it exits for good, the last worker error is returned.
+func (s *Supervisor) Run() error {
+	return s.RunContext(context.Background())
+}
+
+// RunContext runs the worker as Run, a worker.ContextWorker is run with ctx. Once ctx
+// is done the worker is not restarted and the error of ctx is returned.
+func (s *Supervisor) RunContext(ctx context.Context) error {
+	// a worker stopped before it started is never run
+	if s.stopped() {
+		return nil
+	}
+	for {
+		err := s.runOnce(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if s.stopped() {
+			return nil
+		}
+		if !s.shouldRestart(err) {
+			return err
+		}
+		attempt, ok := s.allowRestart()
+		if !ok {
+			s.config.logger.Error("worker restart budget exhausted", xlog.FieldMod(modSupervisor), xlog.FieldName(s.config.Name), xlog.Int("restarts", s.Status().Restarts), xlog.FieldErr(err))
+			if err == nil {
+				return ErrRestartBudget
+			}
+			return fmt.Errorf("%w: %v", ErrRestartBudget, err)
+		}
+
+		backoff := s.backoff(attempt)
+		s.config.logger.Warn("worker restart", xlog.FieldMod(modSupervisor), xlog.FieldName(s.config.Name), xlog.Int("restarts", s.Status().Restarts), xlog.Duration("backoff", backoff), xlog.FieldErr(err))
+		select {
+		case <-time.After(backoff):
+		case <-s.stop:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Stop stops supervising and stops the worker.
+func (s *Supervisor) Stop() error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	return s.worker.Stop()
+}
+
+// Status returns the worker status.
+func (s *Supervisor) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+func (s *Supervisor) runOnce(ctx context.Context) (err error) {
+	s.mu.Lock()
+	s.status.Running = true
+	s.status.LastStart = time.Now()
+	s.mu.Unlock()
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			pe := &PanicError{Value: rec, Stack: debug.Stack()}
+			s.config.logger.Error("worker panic", xlog.FieldMod(modSupervisor), xlog.FieldName(s.config.Name), xlog.Any("panic", rec), xlog.FieldStack(pe.Stack))
+			err = pe
+		}
+
+		s.mu.Lock()
+		s.status.Running = false
+		s.status.LastExit = time.Now()
+		if err != nil {
+			s.status.LastError = err.Error()
+		}
+		s.mu.Unlock()
+	}()
+	if w, ok := s.worker.(worker.ContextWorker); ok {
+		return w.RunContext(ctx)
+	}
+	return s.worker.Run()
+}
+
+func (s *Supervisor) stopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Supervisor) shouldRestart(err error) bool {
+	switch s.config.Policy {
+	case PolicyAlways:
+		return true
+	case PolicyOnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+// allowRestart records a restart and returns how many restarts happened before it
+// within current window, false if the restart budget of the window is used up.
+func (s *Supervisor) allowRestart() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.config.Window > 0 {
+		kept := s.restarts[:0]
+		for _, t := range s.restarts {
+			if now.Sub(t) < s.config.Window {
+				kept = append(kept, t)
+			}
+		}
+		s.restarts = kept
+	}
+	if s.config.MaxRestarts > 0 && len(s.restarts) >= s.config.MaxRestarts {
+		return len(s.restarts), false
+	}
+	s.restarts = append(s.restarts, now)
+	s.status.Restarts++
+	return len(s.restarts) - 1, true
+}
+
+func (s *Supervisor) backoff(attempt int) time.Duration {
+	return xtime.Backoff{
+		Min:        s.config.MinBackoff,
+		Max:        s.config.MaxBackoff,
+		Multiplier: s.config.Multiplier,
+		Jitter:     s.config.Jitter,
+	}.Duration(attempt)
+}
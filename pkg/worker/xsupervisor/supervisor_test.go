@@ -0,0 +1,169 @@
+package xsupervisor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeWorker struct {
+	runs  int32
+	run   func(n int32) error
+	block chan struct{}
+}
+
+func (w *fakeWorker) Run() error {
+	return w.run(atomic.AddInt32(&w.runs, 1))
+}
+
+func (w *fakeWorker) Stop() error {
+	if w.block != nil {
+		close(w.block)
+	}
+	return nil
+}
+
+func testConfig(policy Policy) *Config {
+	config := DefaultConfig()
+	config.Name = "fake"
+	config.Policy = policy
+	config.MinBackoff = time.Millisecond
+	config.MaxBackoff = 5 * time.Millisecond
+	return config
+}
+
+func TestSupervisor_OnFailure(t *testing.T) {
+	w := &fakeWorker{run: func(n int32) error {
+		if n < 3 {
+			return errors.New("boom")
+		}
+		return nil
+	}}
+	s := testConfig(PolicyOnFailure).Build(w)
+	assert.Nil(t, s.Run())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&w.runs))
+
+	status := s.Status()
+	assert.Equal(t, 2, status.Restarts)
+	assert.Equal(t, "boom", status.LastError)
+	assert.False(t, status.Running)
+}
+
+func TestSupervisor_Never(t *testing.T) {
+	w := &fakeWorker{run: func(int32) error {
+		return errors.New("boom")
+	}}
+	s := testConfig(PolicyNever).Build(w)
+	assert.EqualError(t, s.Run(), "boom")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&w.runs))
+}
+
+func TestSupervisor_Budget(t *testing.T) {
+	w := &fakeWorker{run: func(int32) error {
+		return errors.New("boom")
+	}}
+	config := testConfig(PolicyAlways)
+	config.MaxRestarts = 3
+	s := config.Build(w)
+
+	err := s.Run()
+	assert.True(t, errors.Is(err, ErrRestartBudget))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&w.runs))
+	assert.Equal(t, 3, s.Status().Restarts)
+}
+
+func TestSupervisor_Panic(t *testing.T) {
+	w := &fakeWorker{run: func(int32) error {
+		panic("nil map")
+	}}
+	s := testConfig(PolicyNever).Build(w)
+
+	err := s.Run()
+	var pe *PanicError
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, "nil map", pe.Value)
+	assert.NotEmpty(t, pe.Stack)
+}
+
+func TestSupervisor_Stop(t *testing.T) {
+	w := &fakeWorker{block: make(chan struct{})}
+	w.run = func(int32) error {
+		<-w.block
+		return errors.New("stopped")
+	}
+	s := testConfig(PolicyAlways).Build(w)
+
+	done := make(chan error)
+	go func() { done <- s.Run() }()
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, s.Status().Running)
+	assert.Nil(t, s.Stop())
+
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("supervisor not stopped")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&w.runs))
+}
+
+type contextWorker struct {
+	fakeWorker
+}
+
+func (w *contextWorker) RunContext(ctx context.Context) error {
+	atomic.AddInt32(&w.runs, 1)
+	<-ctx.Done()
+	return errors.New("interrupted")
+}
+
+func TestSupervisor_RunContext(t *testing.T) {
+	w := &contextWorker{}
+	s := testConfig(PolicyAlways).Build(w)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.RunContext(ctx) }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("supervisor not cancelled")
+	}
+	// a cancelled worker is not restarted
+	assert.Equal(t, int32(1), atomic.LoadInt32(&w.runs))
+}
+
+func TestSupervisor_Backoff(t *testing.T) {
+	config := testConfig(PolicyAlways)
+	config.MinBackoff = 10 * time.Millisecond
+	config.MaxBackoff = 50 * time.Millisecond
+	config.Jitter = 0
+	s := config.Build(&fakeWorker{})
+
+	assert.Equal(t, 10*time.Millisecond, s.backoff(0))
+	assert.Equal(t, 40*time.Millisecond, s.backoff(2))
+	assert.Equal(t, 50*time.Millisecond, s.backoff(5))
+}
+
+func TestSupervisor_StopBeforeRun(t *testing.T) {
+	w := &fakeWorker{run: func(int32) error { return nil }}
+	s := testConfig(PolicyAlways).Build(w)
+	assert.Nil(t, s.Stop())
+	assert.Nil(t, s.Run())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&w.runs))
+}
+
+func TestStdConfig_Missing(t *testing.T) {
+	config := StdConfig("missing")
+	assert.Equal(t, "missing", config.Name)
+	assert.Equal(t, DefaultConfig().Policy, config.Policy)
+}
@@ -1,6 +1,7 @@
 package xlog
 
 import (
+	"copy/pkg/conf"
 	"fmt"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -8,20 +9,20 @@ import (
 )
 
 type Config struct {
-	Dir           string
-	Name          string
-	Level         string
+	Dir           string `validate:"writable"`
+	Name          string `validate:"required"`
+	Level         string `validate:"oneof=debug info warn error dpanic panic fatal"`
 	Fields        []zap.Field
 	AddCaller     bool
 	Prefix        string
-	MaxSize       int
-	MaxAge        int
-	MaxBackup     int
-	Interval      time.Duration
-	CallerSkip    int
+	MaxSize       int           `validate:"min=1"`
+	MaxAge        int           `validate:"min=0"`
+	MaxBackup     int           `validate:"min=0"`
+	Interval      time.Duration `validate:"min=0s"`
+	CallerSkip    int           `validate:"min=0"`
 	Async         bool
 	Queue         bool
-	QueueSleep    time.Duration
+	QueueSleep    time.Duration `validate:"min=0s"`
 	Core          zapcore.Core
 	Debug         bool
 	EncoderConfig *zapcore.EncoderConfig
@@ -34,7 +35,7 @@ func (config *Config) Filename() string {
 
 func RawConfig(key string) *Config {
 	var config = DefaultConfig()
-	if err := conf.Unmarshalkey(key, &config); err != nil {
+	if err := conf.UnmarshalKey(key, &config); err != nil && !conf.IsValidationError(err) {
 		panic(err)
 	}
 	config.configKey = key
@@ -62,3 +63,24 @@ func DefaultConfig() *Config {
 		EncoderConfig: DefaultZapConfig(),
 	}
 }
+
+func (config Config) Build() *Logger {
+	if config.EncoderConfig == nil {
+		config.EncoderConfig = DefaultZapConfig()
+	}
+	if config.Debug {
+		config.EncoderConfig.EncodeLevel = DebugEncodeLevel
+	}
+	logger := newLogger(&config)
+	if config.configKey != "" {
+		logger.AutoLevel(config.configKey + ".level")
+	}
+	// named loggers can change their level at runtime, see ChangeLevel. Loggers of
+	// StdConfig share the default Name, they are told apart by their config key.
+	if config.configKey != "" {
+		loggers.register(config.configKey, logger)
+	} else if config.Name != "" {
+		loggers.register(config.Name, logger)
+	}
+	return logger
+}
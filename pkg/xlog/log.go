@@ -1,10 +1,17 @@
 package xlog
 
 import (
+	"copy/pkg/conf"
 	"copy/pkg/defers"
+	"copy/pkg/util/xcolor"
+	"fmt"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"log"
 	"os"
+	"runtime"
+	"strings"
+	"time"
 )
 
 const (
@@ -38,6 +45,8 @@ var (
 	Int64  = zap.Int64
 	// Int ...
 	Int = zap.Int
+	// Bool ...
+	Bool = zap.Bool
 	// Int32 ...
 	Int32 = zap.Int32
 	// Uint ...
@@ -81,6 +90,8 @@ func newLogger(config *Config) *Logger {
 
 		defers.Register(close)
 	}
+	// entries are redacted as encoded, before they are buffered
+	ws = redactWriter{ws}
 
 	lv := zap.NewAtomicLevelAt(zapcore.InfoLevel)
 	if err := lv.UnmarshalText([]byte(config.Level)); err != nil {
@@ -88,7 +99,9 @@ func newLogger(config *Config) *Logger {
 	}
 	encoderConfig := *config.EncoderConfig
 	core := config.Core
-	if core == nil {
+	if core != nil {
+		core = redactCore{core}
+	} else {
 		core = zapcore.NewCore(
 			func() zapcore.Encoder {
 				if config.Debug {
@@ -101,10 +114,7 @@ func newLogger(config *Config) *Logger {
 		)
 	}
 
-	zapLogger := zap.New(
-		core,
-		zapOptions...
-	)
+	zapLogger := zap.New(core, zapOptions...)
 	return &Logger{
 		desugar: zapLogger,
 		lv:      &lv,
@@ -112,3 +122,284 @@ func newLogger(config *Config) *Logger {
 		sugar:   zapLogger.Sugar(),
 	}
 }
+
+// AutoLevel updates the level of logger when confKey changes, a change to an unknown level is rejected.
+// A change cancels the pending revert of a level changed with a TTL, see ChangeLevel.
+func (logger *Logger) AutoLevel(confKey string) {
+	conf.Validate(func(config *conf.Configuration) error {
+		lvText := strings.ToLower(config.GetString(confKey))
+		if lvText == "" {
+			return nil
+		}
+		var lv Level
+		if err := lv.UnmarshalText([]byte(lvText)); err != nil {
+			return fmt.Errorf("%s: %w", confKey, err)
+		}
+		return nil
+	})
+	conf.OnChange(confKey, func(_, value interface{}) {
+		lvText := strings.ToLower(fmt.Sprint(value))
+		if value != nil && lvText != "" {
+			logger.Info("update level", String("level", lvText), String("name", logger.config.Name))
+			// cancelled first, a revert firing meanwhile can't overwrite the new level
+			loggers.keep(logger)
+			logger.lv.UnmarshalText([]byte(lvText))
+		}
+	})
+}
+
+// SetLevel ...
+func (logger *Logger) SetLevel(lv Level) {
+	logger.lv.SetLevel(lv)
+}
+
+// Level ...
+func (logger *Logger) Level() Level {
+	return logger.lv.Level()
+}
+
+// Flush ...
+func (logger *Logger) Flush() error {
+	return logger.desugar.Sync()
+}
+
+// DefaultZapConfig ...
+func DefaultZapConfig() *zapcore.EncoderConfig {
+	return &zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "lv",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stack",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     timeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+// DebugEncodeLevel ...
+func DebugEncodeLevel(lv zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	var colorize = xcolor.Red
+	switch lv {
+	case zapcore.DebugLevel:
+		colorize = xcolor.Blue
+	case zapcore.InfoLevel:
+		colorize = xcolor.Green
+	case zapcore.WarnLevel:
+		colorize = xcolor.Yellow
+	case zapcore.ErrorLevel, zap.PanicLevel, zap.DPanicLevel, zap.FatalLevel:
+		colorize = xcolor.Red
+	default:
+	}
+	enc.AppendString(colorize(lv.CapitalString()))
+}
+
+func timeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt64(t.Unix())
+}
+
+// IsDebugMode ...
+func (logger *Logger) IsDebugMode() bool {
+	return logger.config.Debug
+}
+
+func normalizeMessage(msg string) string {
+	return fmt.Sprintf("%-32s", msg)
+}
+
+// Debug ...
+func (logger *Logger) Debug(msg string, fields ...Field) {
+	if logger.IsDebugMode() {
+		msg = normalizeMessage(msg)
+	}
+	logger.desugar.Debug(msg, fields...)
+}
+
+// Debugw ...
+func (logger *Logger) Debugw(msg string, keysAndValues ...interface{}) {
+	if logger.IsDebugMode() {
+		msg = normalizeMessage(msg)
+	}
+	logger.sugar.Debugw(msg, keysAndValues...)
+}
+
+func sprintf(template string, args ...interface{}) string {
+	msg := template
+	if msg == "" && len(args) > 0 {
+		msg = fmt.Sprint(args...)
+	} else if msg != "" && len(args) > 0 {
+		msg = fmt.Sprintf(template, args...)
+	}
+	return msg
+}
+
+// StdLog ...
+func (logger *Logger) StdLog() *log.Logger {
+	return zap.NewStdLog(logger.desugar)
+}
+
+// Debugf ...
+func (logger *Logger) Debugf(template string, args ...interface{}) {
+	logger.sugar.Debugw(sprintf(template, args...))
+}
+
+// Info ...
+func (logger *Logger) Info(msg string, fields ...Field) {
+	if logger.IsDebugMode() {
+		msg = normalizeMessage(msg)
+	}
+	logger.desugar.Info(msg, fields...)
+}
+
+// Infow ...
+func (logger *Logger) Infow(msg string, keysAndValues ...interface{}) {
+	if logger.IsDebugMode() {
+		msg = normalizeMessage(msg)
+	}
+	logger.sugar.Infow(msg, keysAndValues...)
+}
+
+// Infof ...
+func (logger *Logger) Infof(template string, args ...interface{}) {
+	logger.sugar.Infof(sprintf(template, args...))
+}
+
+// Warn ...
+func (logger *Logger) Warn(msg string, fields ...Field) {
+	if logger.IsDebugMode() {
+		msg = normalizeMessage(msg)
+	}
+	logger.desugar.Warn(msg, fields...)
+}
+
+// Warnw ...
+func (logger *Logger) Warnw(msg string, keysAndValues ...interface{}) {
+	if logger.IsDebugMode() {
+		msg = normalizeMessage(msg)
+	}
+	logger.sugar.Warnw(msg, keysAndValues...)
+}
+
+// Warnf ...
+func (logger *Logger) Warnf(template string, args ...interface{}) {
+	logger.sugar.Warnf(sprintf(template, args...))
+}
+
+// Error ...
+func (logger *Logger) Error(msg string, fields ...Field) {
+	if logger.IsDebugMode() {
+		msg = normalizeMessage(msg)
+	}
+	logger.desugar.Error(msg, fields...)
+}
+
+// Errorw ...
+func (logger *Logger) Errorw(msg string, keysAndValues ...interface{}) {
+	if logger.IsDebugMode() {
+		msg = normalizeMessage(msg)
+	}
+	logger.sugar.Errorw(msg, keysAndValues...)
+}
+
+// Errorf ...
+func (logger *Logger) Errorf(template string, args ...interface{}) {
+	logger.sugar.Errorf(sprintf(template, args...))
+}
+
+// Panic ...
+func (logger *Logger) Panic(msg string, fields ...Field) {
+	if logger.IsDebugMode() {
+		panicDetail(msg, fields...)
+		msg = normalizeMessage(msg)
+	}
+	logger.desugar.Panic(msg, fields...)
+}
+
+// Panicw ...
+func (logger *Logger) Panicw(msg string, keysAndValues ...interface{}) {
+	if logger.IsDebugMode() {
+		msg = normalizeMessage(msg)
+	}
+	logger.sugar.Panicw(msg, keysAndValues...)
+}
+
+// Panicf ...
+func (logger *Logger) Panicf(template string, args ...interface{}) {
+	logger.sugar.Panicf(sprintf(template, args...))
+}
+
+// DPanic ...
+func (logger *Logger) DPanic(msg string, fields ...Field) {
+	if logger.IsDebugMode() {
+		panicDetail(msg, fields...)
+		msg = normalizeMessage(msg)
+	}
+	logger.desugar.DPanic(msg, fields...)
+}
+
+// DPanicw ...
+func (logger *Logger) DPanicw(msg string, keysAndValues ...interface{}) {
+	if logger.IsDebugMode() {
+		msg = normalizeMessage(msg)
+	}
+	logger.sugar.DPanicw(msg, keysAndValues...)
+}
+
+// DPanicf ...
+func (logger *Logger) DPanicf(template string, args ...interface{}) {
+	logger.sugar.DPanicf(sprintf(template, args...))
+}
+
+// Fatal ...
+func (logger *Logger) Fatal(msg string, fields ...Field) {
+	if logger.IsDebugMode() {
+		panicDetail(msg, fields...)
+		msg = normalizeMessage(msg)
+		return
+	}
+	logger.desugar.Fatal(msg, fields...)
+}
+
+// Fatalw ...
+func (logger *Logger) Fatalw(msg string, keysAndValues ...interface{}) {
+	if logger.IsDebugMode() {
+		msg = normalizeMessage(msg)
+	}
+	logger.sugar.Fatalw(msg, keysAndValues...)
+}
+
+// Fatalf ...
+func (logger *Logger) Fatalf(template string, args ...interface{}) {
+	logger.sugar.Fatalf(sprintf(template, args...))
+}
+
+func panicDetail(msg string, fields ...Field) {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	// 控制台输出
+	fmt.Printf("%s: \n    %s: %s\n", xcolor.Red("panic"), xcolor.Red("msg"), msg)
+	if _, file, line, ok := runtime.Caller(3); ok {
+		fmt.Printf("    %s: %s:%d\n", xcolor.Red("loc"), file, line)
+	}
+	for key, val := range enc.Fields {
+		fmt.Printf("    %s: %s\n", xcolor.Red(key), fmt.Sprintf("%+v", val))
+	}
+
+}
+
+// With ...
+func (logger *Logger) With(fields ...Field) *Logger {
+	desugarLogger := logger.desugar.With(fields...)
+	return &Logger{
+		desugar: desugarLogger,
+		lv:      logger.lv,
+		sugar:   desugarLogger.Sugar(),
+		config:  logger.config,
+	}
+}
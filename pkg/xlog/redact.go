@@ -0,0 +1,188 @@
+package xlog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// redactMask replaces a redacted value in log output
+const redactMask = "******"
+
+// minRedactLen is the length of the shortest redacted value, a shorter one such as
+// "true" would mask unrelated output and is skipped
+const minRedactLen = 6
+
+const modRedact = "xlog.redact"
+
+var (
+	redactMu sync.Mutex
+	// redacted holds a *strings.Replacer, nil if nothing is redacted
+	redacted     atomic.Value
+	redactValues []string
+)
+
+// Redact hides values, such as decrypted secrets, in everything written by the
+// loggers built by Config.Build: the encoded entries are scrubbed, which covers the
+// message and fields of any type, such as Any, Reflect and Object. A logger of a
+// custom Config.Core only gets its message and its string, error and stringer fields
+// redacted, see redactCore. Values shorter than 6 bytes are skipped with a warning.
+func Redact(values ...string) {
+	redactMu.Lock()
+	skipped := addRedact(values)
+	redactMu.Unlock()
+	warnSkipped(skipped)
+}
+
+// SetRedacted replaces the redacted values by values, such as the secrets still live
+// after a config reload, see Redact.
+func SetRedacted(values ...string) {
+	redactMu.Lock()
+	redactValues = nil
+	skipped := addRedact(values)
+	redactMu.Unlock()
+	warnSkipped(skipped)
+}
+
+// addRedact adds values to the redacted ones and returns how many are too short, redactMu is held
+func addRedact(values []string) (skipped int) {
+	for _, v := range values {
+		switch {
+		case v == "":
+		case len(v) < minRedactLen:
+			skipped++
+		case !containsString(redactValues, v):
+			redactValues = append(redactValues, v)
+		}
+	}
+	// longer values first, a value containing another one is masked whole
+	sort.SliceStable(redactValues, func(i, j int) bool {
+		return len(redactValues[i]) > len(redactValues[j])
+	})
+	pairs := make([]string, 0, 4*len(redactValues))
+	for _, v := range redactValues {
+		pairs = append(pairs, v, redactMask)
+		// values inside JSON strings are escaped by the encoder
+		if escaped := jsonEscape(v); escaped != v {
+			pairs = append(pairs, escaped, redactMask)
+		}
+	}
+	if len(pairs) == 0 {
+		redacted.Store((*strings.Replacer)(nil))
+	} else {
+		redacted.Store(strings.NewReplacer(pairs...))
+	}
+	return skipped
+}
+
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func warnSkipped(skipped int) {
+	if skipped > 0 && JupiterLogger != nil {
+		JupiterLogger.Warn("values too short to redact", FieldMod(modRedact), Int("values", skipped), Int("minLength", minRedactLen))
+	}
+}
+
+// jsonEscape escapes s the way the JSON encoder of zap does in strings
+func jsonEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '"':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// redactWriter redacts the encoded entries written to its WriteSyncer
+type redactWriter struct {
+	zapcore.WriteSyncer
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	r, _ := redacted.Load().(*strings.Replacer)
+	if r == nil {
+		return w.WriteSyncer.Write(p)
+	}
+	if _, err := w.WriteSyncer.Write([]byte(r.Replace(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func redactString(s string) string {
+	r, _ := redacted.Load().(*strings.Replacer)
+	if r == nil {
+		return s
+	}
+	return r.Replace(s)
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	if r, _ := redacted.Load().(*strings.Replacer); r == nil {
+		return fields
+	}
+	redactedFields := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			f.String = redactString(f.String)
+		case zapcore.ByteStringType:
+			f = zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: redactString(string(f.Interface.([]byte)))}
+		case zapcore.ErrorType:
+			if err, ok := f.Interface.(error); ok && err != nil {
+				f = zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: redactString(err.Error())}
+			}
+		case zapcore.StringerType:
+			if s, ok := f.Interface.(fmt.Stringer); ok && s != nil {
+				f = zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: redactString(s.String())}
+			}
+		}
+		redactedFields[i] = f
+	}
+	return redactedFields
+}
+
+// redactCore redacts the entries written to a custom core, whose output is not known
+type redactCore struct {
+	zapcore.Core
+}
+
+func (c redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return redactCore{c.Core.With(redactFields(fields))}
+}
+
+func (c redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = redactString(ent.Message)
+	return c.Core.Write(ent, redactFields(fields))
+}
@@ -0,0 +1,69 @@
+package xlog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedact(t *testing.T) {
+	SetRedacted()
+	t.Cleanup(func() { SetRedacted() })
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(redactCore{core})
+
+	logger.Info("connect p@ssw0rd")
+	Redact("p@ssw0rd", "")
+	logger = logger.With(String("dsn", "root:p@ssw0rd@tcp(db)"))
+	logger.Info("connect p@ssw0rd", FieldErr(errors.New("auth p@ssw0rd failed")), ByteString("raw", []byte("p@ssw0rd")), Int("port", 3306))
+
+	entries := logs.AllUntimed()
+	assert.Equal(t, "connect p@ssw0rd", entries[0].Message)
+	assert.Equal(t, "connect ******", entries[1].Message)
+	assert.Equal(t, map[string]interface{}{
+		"dsn":   "root:******@tcp(db)",
+		"error": "auth ****** failed",
+		"raw":   "******",
+		"port":  int64(3306),
+	}, entries[1].ContextMap())
+}
+
+func TestRedact_Encoded(t *testing.T) {
+	SetRedacted()
+	t.Cleanup(func() { SetRedacted() })
+	var buf bytes.Buffer
+	encoder := zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	logger := zap.New(zapcore.NewCore(encoder, redactWriter{zapcore.AddSync(&buf)}, zapcore.DebugLevel))
+
+	Redact(`p@"ssw0rd`)
+	logger.Info("connect",
+		Any("dsn", map[string]string{"password": `p@"ssw0rd`}),
+		Reflect("conf", struct{ Password string }{`p@"ssw0rd`}),
+		Any("args", []string{"--password", `p@"ssw0rd`}),
+	)
+
+	assert.Equal(t, `{"msg":"connect","dsn":{"password":"******"},"conf":{"Password":"******"},"args":["--password","******"]}`+"\n", buf.String())
+}
+
+func TestSetRedacted(t *testing.T) {
+	SetRedacted()
+	t.Cleanup(func() { SetRedacted() })
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(redactCore{core})
+
+	// short values would mask unrelated output
+	Redact("true", "old-secret")
+	logger.Info("enabled true, key old-secret")
+	// values no longer live are logged again
+	SetRedacted("new-secret")
+	logger.Info("key old-secret, new-secret")
+
+	entries := logs.AllUntimed()
+	assert.Equal(t, "enabled true, key ******", entries[0].Message)
+	assert.Equal(t, "key old-secret, ******", entries[1].Message)
+}
@@ -0,0 +1,148 @@
+package xlog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ModAudit is the mod of the audit entries of level changes
+const ModAudit = "xlog.audit"
+
+// loggers are the named loggers built by Config.Build by the config key they are read
+// from, or by Name if not read by RawConfig. A logger built later replaces the one of
+// the same key.
+var loggers = &registry{entries: make(map[string]*entry)}
+
+type registry struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	name   string
+	logger *Logger
+	// base is the level restored at revertAt, set while a change with a TTL is pending
+	base     Level
+	revertAt time.Time
+	timer    *time.Timer
+}
+
+// LoggerLevel is the level of a named logger, Name is its registry key, RevertAt is when a temporary level
+// reverts, zero if the level is not temporary.
+type LoggerLevel struct {
+	Name     string    `json:"name"`
+	Level    string    `json:"level"`
+	RevertAt time.Time `json:"revertAt,omitempty"`
+}
+
+// LevelChange is a level change made by an operator.
+type LevelChange struct {
+	Name  string
+	Level string
+	// TTL reverts the level after it unless it is 0, a later change replaces a pending revert,
+	// and a level set by a config reload cancels it
+	TTL time.Duration
+	// Operator tells who made the change in the audit log
+	Operator string
+}
+
+func (r *registry) register(name string, logger *Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.entries[name]; ok && old.timer != nil {
+		old.timer.Stop()
+	}
+	r.entries[name] = &entry{name: name, logger: logger}
+}
+
+// Loggers returns the levels of the named loggers sorted by name.
+func Loggers() []LoggerLevel {
+	loggers.mu.Lock()
+	defer loggers.mu.Unlock()
+	levels := make([]LoggerLevel, 0, len(loggers.entries))
+	for name, e := range loggers.entries {
+		levels = append(levels, LoggerLevel{Name: name, Level: e.logger.Level().String(), RevertAt: e.revertAt})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].Name < levels[j].Name
+	})
+	return levels
+}
+
+// Lookup returns the named logger of name.
+func Lookup(name string) (*Logger, bool) {
+	loggers.mu.Lock()
+	defer loggers.mu.Unlock()
+	e, ok := loggers.entries[name]
+	if !ok {
+		return nil, false
+	}
+	return e.logger, true
+}
+
+// ChangeLevel sets the level of the named logger of change.Name. With a TTL the
+// level reverts to the one before the first pending change. Changes and reverts are
+// written to JupiterLogger as audit entries of ModAudit.
+func ChangeLevel(change LevelChange) error {
+	var lv Level
+	if err := lv.UnmarshalText([]byte(strings.ToLower(change.Level))); err != nil {
+		return err
+	}
+	if change.TTL < 0 {
+		return fmt.Errorf("negative ttl %s", change.TTL)
+	}
+
+	loggers.mu.Lock()
+	defer loggers.mu.Unlock()
+	e, ok := loggers.entries[change.Name]
+	if !ok {
+		return fmt.Errorf("logger %q not found", change.Name)
+	}
+	from := e.logger.Level()
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	} else {
+		e.base = from
+	}
+	e.revertAt = time.Time{}
+	if change.TTL > 0 {
+		e.revertAt = time.Now().Add(change.TTL)
+		e.timer = time.AfterFunc(change.TTL, func() {
+			loggers.revert(e)
+		})
+	}
+	e.logger.SetLevel(lv)
+	JupiterLogger.Info("change level", FieldMod(ModAudit), FieldName(change.Name), String("from", from.String()), String("to", lv.String()), Duration("ttl", change.TTL), String("operator", change.Operator))
+	return nil
+}
+
+// revert restores the base level of e unless a later change replaced its timer
+func (r *registry) revert(e *entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e.timer == nil || time.Now().Before(e.revertAt) {
+		return
+	}
+	from := e.logger.Level()
+	e.timer = nil
+	e.revertAt = time.Time{}
+	e.logger.SetLevel(e.base)
+	JupiterLogger.Info("revert level", FieldMod(ModAudit), FieldName(e.name), String("from", from.String()), String("to", e.base.String()), String("operator", "ttl"))
+}
+
+// keep cancels the pending revert of logger, a level set by a config reload is kept
+func (r *registry) keep(logger *Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		if e.logger == logger && e.timer != nil {
+			e.timer.Stop()
+			e.timer = nil
+			e.revertAt = time.Time{}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package xlog
+
+import (
+	"copy/pkg/conf"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestChangeLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	jupiter := JupiterLogger
+	JupiterLogger = Config{Name: "jupiter", Core: core}.Build()
+	defer func() { JupiterLogger = jupiter }()
+
+	logger := Config{Name: "test.log", Level: "info", Core: core}.Build()
+	got, ok := Lookup("test.log")
+	assert.True(t, ok)
+	assert.Same(t, logger, got)
+	assert.Contains(t, Loggers(), LoggerLevel{Name: "test.log", Level: "info"})
+
+	assert.NotNil(t, ChangeLevel(LevelChange{Name: "test.log", Level: "verbose"}))
+	assert.NotNil(t, ChangeLevel(LevelChange{Name: "missing", Level: "debug"}))
+
+	// a later change replaces the pending revert, the level before the first change is restored
+	assert.Nil(t, ChangeLevel(LevelChange{Name: "test.log", Level: "debug", TTL: time.Hour, Operator: "alice"}))
+	assert.Nil(t, ChangeLevel(LevelChange{Name: "test.log", Level: "warn", TTL: 50 * time.Millisecond, Operator: "bob"}))
+	assert.Equal(t, WarnLevel, logger.Level())
+	for _, level := range Loggers() {
+		if level.Name == "test.log" {
+			assert.False(t, level.RevertAt.IsZero())
+		}
+	}
+	assert.Eventually(t, func() bool {
+		return logger.Level() == InfoLevel
+	}, time.Second, 10*time.Millisecond)
+
+	audits := logs.FilterField(FieldMod(ModAudit)).AllUntimed()
+	if assert.Len(t, audits, 3) {
+		assert.Equal(t, "alice", audits[0].ContextMap()["operator"])
+		assert.Equal(t, "bob", audits[1].ContextMap()["operator"])
+		assert.Equal(t, "revert level", audits[2].Message)
+		assert.Equal(t, "info", audits[2].ContextMap()["to"])
+	}
+
+	// a change without TTL is kept
+	assert.Nil(t, ChangeLevel(LevelChange{Name: "test.log", Level: "error"}))
+	assert.Equal(t, ErrorLevel, logger.Level())
+}
+
+func TestChangeLevel_ConfigReload(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	jupiter := JupiterLogger
+	JupiterLogger = Config{Name: "jupiter", Core: core}.Build()
+	defer func() { JupiterLogger = jupiter }()
+
+	assert.Nil(t, conf.SetLayer("reload.toml", conf.PriorityFile, map[string]interface{}{
+		"test": map[string]interface{}{"logger": map[string]interface{}{"reload": map[string]interface{}{"level": "info"}}},
+	}))
+	defer conf.SetLayer("reload.toml", conf.PriorityFile, nil)
+	logger := Config{Level: "info", Core: core, configKey: "test.logger.reload"}.Build()
+
+	// a level reloaded from config cancels the pending revert
+	assert.Nil(t, ChangeLevel(LevelChange{Name: "test.logger.reload", Level: "debug", TTL: 50 * time.Millisecond, Operator: "alice"}))
+	assert.Nil(t, conf.SetLayer("reload.toml", conf.PriorityFile, map[string]interface{}{
+		"test": map[string]interface{}{"logger": map[string]interface{}{"reload": map[string]interface{}{"level": "error"}}},
+	}))
+	assert.Equal(t, ErrorLevel, logger.Level())
+	assert.Contains(t, Loggers(), LoggerLevel{Name: "test.logger.reload", Level: "error"})
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, ErrorLevel, logger.Level())
+
+	assert.Empty(t, logs.FilterMessage("revert level").AllUntimed())
+}
+
+func TestLookup_ConfigKey(t *testing.T) {
+	// loggers read from conf share the default Name
+	a := Config{Name: "default.log", Core: zapcore.NewNopCore(), configKey: "test.logger.a"}.Build()
+	b := Config{Name: "default.log", Core: zapcore.NewNopCore(), configKey: "test.logger.b"}.Build()
+
+	got, ok := Lookup("test.logger.a")
+	assert.True(t, ok)
+	assert.Same(t, a, got)
+	got, ok = Lookup("test.logger.b")
+	assert.True(t, ok)
+	assert.Same(t, b, got)
+
+	assert.Nil(t, ChangeLevel(LevelChange{Name: "test.logger.a", Level: "error"}))
+	assert.Equal(t, ErrorLevel, a.Level())
+	assert.Equal(t, InfoLevel, b.Level())
+}
@@ -0,0 +1,41 @@
+package xlog
+
+import (
+	"io"
+	"sync"
+
+	"github.com/douyu/jupiter/pkg/xlog/rotate"
+	"go.uber.org/multierr"
+)
+
+var (
+	rotatesMu sync.Mutex
+	rotates   = make([]*rotate.Logger, 0)
+)
+
+func newRotate(config *Config) io.Writer {
+	rotateLog := rotate.NewLogger()
+	rotateLog.Filename = config.Filename()
+	rotateLog.MaxSize = config.MaxSize // MB
+	rotateLog.MaxAge = config.MaxAge   // days
+	rotateLog.MaxBackups = config.MaxBackup
+	rotateLog.Interval = config.Interval
+	rotateLog.LocalTime = true
+	rotateLog.Compress = false
+
+	rotatesMu.Lock()
+	rotates = append(rotates, rotateLog)
+	rotatesMu.Unlock()
+	return rotateLog
+}
+
+// Reopen closes the log files of every file logger, each file is opened again on next write.
+func Reopen() error {
+	rotatesMu.Lock()
+	defer rotatesMu.Unlock()
+	var errs error
+	for _, r := range rotates {
+		errs = multierr.Append(errs, r.Close())
+	}
+	return errs
+}
@@ -0,0 +1,56 @@
+package copy
+
+import (
+	"copy/pkg/conf"
+	"copy/pkg/secret"
+	"copy/pkg/xlog"
+	"fmt"
+
+	"github.com/douyu/jupiter/pkg/ecode"
+)
+
+// SecretKeysEnv is the environment variable of secret keys, id:base64(key) separated by comma
+const SecretKeysEnv = "JUPITER_SECRET_KEYS"
+
+// initSecrets sets the decrypter of ENC(...) config values with the keys of
+// --secret-key-file and $JUPITER_SECRET_KEYS, the key file is looked up first.
+// Keys are read again before every config load, at startup, on SIGHUP and on every
+// --watch reload, so that a rotated key file takes effect. Decrypted values are
+// redacted from every logger, see redactSecrets.
+func (app *Application) initSecrets() error {
+	var keyring secret.Chain
+	if path := app.flags.String("secret-key-file"); path != "" {
+		keys, err := secret.FileKeyring(path)
+		if err != nil {
+			return fmt.Errorf("load secret keys: %w", err)
+		}
+		keyring = append(keyring, keys)
+	}
+	keys, err := secret.EnvKeyring(SecretKeysEnv)
+	if err != nil {
+		return fmt.Errorf("load secret keys: %w", err)
+	}
+	if len(keys) > 0 {
+		keyring = append(keyring, keys)
+	}
+	if len(keyring) == 0 {
+		return nil
+	}
+
+	conf.SetDecrypter(func(value string) (string, error) {
+		plaintext, err := secret.Decrypt(keyring, value)
+		if err != nil {
+			return "", err
+		}
+		xlog.Redact(plaintext)
+		return plaintext, nil
+	})
+	app.logger.Info("secret keys loaded", xlog.FieldMod(ecode.ModConfig), xlog.Int("keyrings", len(keyring)))
+	return nil
+}
+
+// redactSecrets redacts the live decrypted values only, those dropped by a reload or
+// a key rotation are forgotten
+func (app *Application) redactSecrets() {
+	xlog.SetRedacted(conf.SecretValues()...)
+}
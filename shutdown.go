@@ -0,0 +1,136 @@
+package copy
+
+import (
+	"context"
+	"copy/pkg/server"
+	"copy/pkg/util/xtime"
+	"copy/pkg/worker"
+	"copy/pkg/worker/xsupervisor"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	// defaultShutdownTimeout bounds GracefulStop when no shutdown timeout is set
+	defaultShutdownTimeout = 30 * time.Second
+	// forceStopTimeout bounds the Stop of a server which missed the shutdown deadline
+	forceStopTimeout = 5 * time.Second
+)
+
+// StopResult is how a server or worker stopped.
+type StopResult struct {
+	Name   string        `json:"name"`
+	Cost   time.Duration `json:"cost"`
+	Forced bool          `json:"forced"`
+	Err    error         `json:"-"`
+}
+
+// ShutdownReport lists how every server and worker stopped in GracefulStop.
+type ShutdownReport struct {
+	Budget  time.Duration `json:"budget"`
+	Cost    time.Duration `json:"cost"`
+	Servers []StopResult  `json:"servers"`
+	Workers []StopResult  `json:"workers"`
+}
+
+// Err combines the stop errors of servers and workers.
+func (r *ShutdownReport) Err() error {
+	var errs error
+	for _, s := range r.Servers {
+		if s.Err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("server %s: %w", s.Name, s.Err))
+		}
+	}
+	for _, w := range r.Workers {
+		if w.Err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("worker %s: %w", w.Name, w.Err))
+		}
+	}
+	return errs
+}
+
+type reportCollector struct {
+	mu     sync.Mutex
+	report *ShutdownReport
+}
+
+func (c *reportCollector) addServer(r StopResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.report.Servers = append(c.report.Servers, r)
+}
+
+func (c *reportCollector) addWorker(r StopResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.report.Workers = append(c.report.Workers, r)
+}
+
+// stopServer stops s gracefully, and forces it through Stop once ctx is done or the
+// graceful stop fails with the error of a context.
+func stopServer(ctx context.Context, clock xtime.Clock, s server.Server) StopResult {
+	start := clock.Now()
+	result := StopResult{Name: s.Info().Label()}
+
+	graceful := make(chan error, 1)
+	go func() {
+		graceful <- s.GracefulStop(ctx)
+	}()
+	var err error
+	select {
+	case err = <-graceful:
+		// a graceful stop which gave up on its deadline is forced as well
+		if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+			result.Cost = clock.Since(start)
+			result.Err = err
+			return result
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result.Forced = true
+	result.Err = fmt.Errorf("graceful stop: %w", err)
+	forced := make(chan error, 1)
+	go func() {
+		forced <- s.Stop()
+	}()
+	select {
+	case err := <-forced:
+		result.Err = multierr.Append(result.Err, err)
+	case <-clock.After(forceStopTimeout):
+		result.Err = multierr.Append(result.Err, fmt.Errorf("force stop timeout after %v", forceStopTimeout))
+	}
+	result.Cost = clock.Since(start)
+	return result
+}
+
+// stopWorker stops w, and gives up waiting once ctx is done.
+func stopWorker(ctx context.Context, clock xtime.Clock, w worker.Worker) StopResult {
+	start := clock.Now()
+	result := StopResult{Name: workerName(w)}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Stop()
+	}()
+	select {
+	case err := <-done:
+		result.Err = err
+	case <-ctx.Done():
+		result.Err = fmt.Errorf("stop: %w", ctx.Err())
+	}
+	result.Cost = clock.Since(start)
+	return result
+}
+
+func workerName(w worker.Worker) string {
+	if s, ok := w.(*xsupervisor.Supervisor); ok {
+		return s.Status().Name
+	}
+	return fmt.Sprintf("%T", w)
+}
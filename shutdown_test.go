@@ -0,0 +1,36 @@
+package copy
+
+import (
+	"context"
+	"copy/pkg/util/xtime"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// drainServer gives up its graceful stop with a deadline error before ctx is done
+type drainServer struct {
+	*testServer
+}
+
+func (s drainServer) GracefulStop(ctx context.Context) error {
+	return fmt.Errorf("drain: %w", context.DeadlineExceeded)
+}
+
+func TestStopServer_GracefulDeadline(t *testing.T) {
+	s := drainServer{newTestServer("drain")}
+	result := stopServer(context.Background(), xtime.SystemClock, s)
+	assert.True(t, result.Forced)
+	assert.EqualError(t, result.Err, "graceful stop: drain: context deadline exceeded")
+	select {
+	case <-s.stopped:
+	default:
+		t.Fatal("server not forced through Stop")
+	}
+
+	s2 := newTestServer("ok")
+	result = stopServer(context.Background(), xtime.SystemClock, s2)
+	assert.False(t, result.Forced)
+	assert.Nil(t, result.Err)
+}
@@ -0,0 +1,103 @@
+package copy
+
+import (
+	"copy/pkg"
+	"copy/pkg/signals"
+	"copy/pkg/xlog"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"time"
+
+	"github.com/douyu/jupiter/pkg/ecode"
+)
+
+// levelCycle is the order SignalLevel walks the default logger level through
+var levelCycle = []xlog.Level{xlog.DebugLevel, xlog.InfoLevel, xlog.WarnLevel, xlog.ErrorLevel}
+
+// initSignals maps the default signal actions:
+// - SIGHUP reloads config and reopens log files
+// - SIGUSR1 writes a goroutine and heap dump into the log dir
+// - SIGUSR2 cycles the level of the default logger
+func (app *Application) initSignals() {
+	app.signals.Handle(signals.SignalReload, app.reloadAction)
+	app.signals.Handle(signals.SignalDump, app.dumpAction)
+	app.signals.Handle(signals.SignalLevel, app.levelAction)
+}
+
+// HandleSignal maps sig to a custom action, the default action of sig is replaced
+func (app *Application) HandleSignal(sig os.Signal, action signals.Action) {
+	app.signals.Handle(sig, action)
+}
+
+// SetForceExitTimeout set how long shutdown may take after the first shutdown signal
+// before the process exits, zero waits for a second signal
+func (app *Application) SetForceExitTimeout(timeout time.Duration) {
+	app.signals.SetForceExitTimeout(timeout)
+}
+
+func (app *Application) reloadAction(sig os.Signal) {
+	app.logger.Info("reload config", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent(sig.String()))
+	if err := app.loadConfig(); err != nil {
+		app.logger.Error("reload config", xlog.FieldMod(ecode.ModConfig), xlog.FieldErr(err))
+	}
+	if err := xlog.Reopen(); err != nil {
+		app.logger.Error("reopen log files", xlog.FieldMod(ecode.ModApp), xlog.FieldErr(err))
+	}
+}
+
+func (app *Application) dumpAction(sig os.Signal) {
+	dir := pkg.AppLogDir()
+	if dir == "" {
+		dir = "."
+	}
+	files, err := dumpProfiles(dir, app.clock.Now())
+	if err != nil {
+		app.logger.Error("dump profiles", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent(sig.String()), xlog.FieldErr(err))
+		return
+	}
+	app.logger.Info("dump profiles", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent(sig.String()), xlog.Any("files", files))
+}
+
+func (app *Application) levelAction(sig os.Signal) {
+	current := xlog.DefaultLogger.Level()
+	next := levelCycle[0]
+	for i, lv := range levelCycle {
+		if lv == current && i+1 < len(levelCycle) {
+			next = levelCycle[i+1]
+		}
+	}
+	xlog.DefaultLogger.SetLevel(next)
+	app.logger.Info("cycle default logger level", xlog.FieldMod(ecode.ModApp), xlog.FieldEvent(sig.String()), xlog.String("from", current.String()), xlog.String("to", next.String()))
+}
+
+// dumpProfiles writes goroutine stacks and a heap profile of now into dir
+func dumpProfiles(dir string, now time.Time) ([]string, error) {
+	suffix := fmt.Sprintf("%d.%s", os.Getpid(), now.Format("20060102150405"))
+	goroutine := filepath.Join(dir, "goroutine."+suffix+".txt")
+	heap := filepath.Join(dir, "heap."+suffix+".pprof")
+
+	if err := writeProfile(goroutine, func(w io.Writer) error {
+		return pprof.Lookup("goroutine").WriteTo(w, 2)
+	}); err != nil {
+		return nil, err
+	}
+	if err := writeProfile(heap, pprof.WriteHeapProfile); err != nil {
+		return nil, err
+	}
+	return []string{goroutine, heap}, nil
+}
+
+func writeProfile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
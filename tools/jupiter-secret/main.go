@@ -0,0 +1,163 @@
+// Command jupiter-secret manages the secret keys and the ENC(...) values of config:
+//
+//	jupiter-secret keygen  -key-file secret.keys -id 2021-06
+//	jupiter-secret encrypt -key-file secret.keys < password.txt
+//	jupiter-secret rotate  -key-file secret.keys config.toml
+//
+// keygen appends a new primary key to the key file, encrypt seals stdin with the
+// primary key, rotate encrypts the ENC(...) values of config files again with the
+// primary key. Keys are read from -key-file and $JUPITER_SECRET_KEYS.
+package main
+
+import (
+	"copy/pkg/secret"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// encrypted matches an ENC(...) value in a config file
+var encrypted = regexp.MustCompile(`ENC\([^)]*\)`)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	keyFile := fs.String("key-file", os.Getenv("JUPITER_SECRET_KEY_FILE"), "key file, one id:base64(key) a line")
+	id := fs.String("id", time.Now().Format("20060102150405"), "id of the generated key")
+	_ = fs.Parse(os.Args[2:])
+
+	var err error
+	switch os.Args[1] {
+	case "keygen":
+		err = keygen(*keyFile, *id)
+	case "encrypt":
+		err = encrypt(*keyFile)
+	case "rotate":
+		err = rotate(*keyFile, fs.Args())
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "jupiter-secret:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: jupiter-secret keygen|encrypt|rotate -key-file <path> [files...]")
+	os.Exit(2)
+}
+
+func keyring(keyFile string) (secret.Keyring, error) {
+	var kr secret.Chain
+	if keyFile != "" {
+		keys, err := secret.FileKeyring(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		kr = append(kr, keys)
+	}
+	keys, err := secret.EnvKeyring("JUPITER_SECRET_KEYS")
+	if err != nil {
+		return nil, err
+	}
+	return append(kr, keys), nil
+}
+
+// keygen appends a new key to keyFile, it becomes the primary key of the file
+func keygen(keyFile, id string) error {
+	if keyFile == "" {
+		return errors.New("-key-file is required")
+	}
+	key, err := secret.NewKey(id)
+	if err != nil {
+		return err
+	}
+	if keys, err := secret.FileKeyring(keyFile); err == nil {
+		if _, err := keys.Key(id); err == nil {
+			return fmt.Errorf("key %s exists", id)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(keyFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, key.String()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Println(id)
+	return nil
+}
+
+// encrypt prints the ENC(...) value of stdin, a trailing newline is trimmed
+func encrypt(keyFile string) error {
+	kr, err := keyring(keyFile)
+	if err != nil {
+		return err
+	}
+	plaintext, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	value, err := secret.Encrypt(kr, strings.TrimRight(string(plaintext), "\r\n"))
+	if err != nil {
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
+
+// rotate rewrites the ENC(...) values of files with the primary key
+func rotate(keyFile string, files []string) error {
+	kr, err := keyring(keyFile)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var rotated int
+		var rotateErr error
+		content = encrypted.ReplaceAllFunc(content, func(value []byte) []byte {
+			if rotateErr != nil {
+				return value
+			}
+			v, err := secret.Rotate(kr, string(value))
+			if err != nil {
+				rotateErr = err
+				return value
+			}
+			if v != string(value) {
+				rotated++
+			}
+			return []byte(v)
+		})
+		if rotateErr != nil {
+			return fmt.Errorf("%s: %w", file, rotateErr)
+		}
+		if err := ioutil.WriteFile(file, content, info.Mode()); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d value(s) rotated\n", file, rotated)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package copy
+
+import (
+	"context"
+	"copy/pkg/server"
+	"copy/pkg/xlog"
+	"errors"
+	"fmt"
+
+	"github.com/douyu/jupiter/pkg/ecode"
+)
+
+// ErrServerNotFound is returned by UpdateServer for a label of no server
+var ErrServerNotFound = errors.New("server not found")
+
+// ServerUpdate changes the registration of a server, nil fields are kept.
+type ServerUpdate struct {
+	// Enable false takes the server out of rotation, it keeps serving the clients
+	// which have not seen the change yet
+	Enable  *bool
+	Healthy *bool
+	Weight  *float64
+}
+
+// UpdateServer applies update to the info of the server of label and registers it
+// again if it is registered, so that the change reaches discovery without a restart.
+// Updates are kept by the app on top of the Info of the server, which is left as is.
+// The updated info is returned.
+func (app *Application) UpdateServer(label string, update ServerUpdate) (server.ServiceInfo, error) {
+	if update.Weight != nil && *update.Weight < 0 {
+		return server.ServiceInfo{}, fmt.Errorf("invalid weight %v", *update.Weight)
+	}
+	var target server.Server
+	app.smu.RLock()
+	for _, s := range app.servers {
+		if s.Info().Label() == label {
+			target = s
+			break
+		}
+	}
+	app.smu.RUnlock()
+	if target == nil {
+		return server.ServiceInfo{}, fmt.Errorf("%s: %w", label, ErrServerNotFound)
+	}
+	return app.updateServer(target, update), nil
+}
+
+func (app *Application) updateServer(s server.Server, update ServerUpdate) server.ServiceInfo {
+	app.rmu.Lock()
+	defer app.rmu.Unlock()
+	label := s.Info().Label()
+	// the fields are copied, update belongs to the caller
+	merged := app.updates[label]
+	if update.Enable != nil {
+		enable := *update.Enable
+		merged.Enable = &enable
+	}
+	if update.Healthy != nil {
+		healthy := *update.Healthy
+		merged.Healthy = &healthy
+	}
+	if update.Weight != nil {
+		weight := *update.Weight
+		merged.Weight = &weight
+	}
+	app.updates[label] = merged
+
+	info := app.serviceInfo(s)
+	fields := []xlog.Field{xlog.FieldMod(ecode.ModApp), xlog.FieldAddr(label), xlog.Bool("enable", info.Enable), xlog.Bool("healthy", info.Healthy), xlog.Any("weight", info.Weight)}
+	if _, ok := app.registered[label]; ok && app.registerer != nil {
+		app.registered[label] = info
+		if err := app.registerer.RegisterService(context.Background(), info); err != nil {
+			app.logger.Error("update service", append(fields, xlog.FieldErr(err))...)
+			return *info
+		}
+	}
+	app.logger.Info("update service", fields...)
+	return *info
+}
+
+// serviceInfo returns a copy of the info of s with its updates applied, app.rmu is held
+func (app *Application) serviceInfo(s server.Server) *server.ServiceInfo {
+	info := *s.Info()
+	update := app.updates[info.Label()]
+	if update.Enable != nil {
+		info.Enable = *update.Enable
+	}
+	if update.Healthy != nil {
+		info.Healthy = *update.Healthy
+	}
+	if update.Weight != nil {
+		info.Weight = *update.Weight
+	}
+	return &info
+}
+
+// serviceInfos returns the infos of every server with their updates applied
+func (app *Application) serviceInfos() []server.ServiceInfo {
+	app.smu.RLock()
+	defer app.smu.RUnlock()
+	app.rmu.Lock()
+	defer app.rmu.Unlock()
+	infos := make([]server.ServiceInfo, 0, len(app.servers))
+	for _, s := range app.servers {
+		infos = append(infos, *app.serviceInfo(s))
+	}
+	return infos
+}